@@ -0,0 +1,111 @@
+package statsd
+
+import (
+	"sync"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxAggregatedSamples is the maximum number of records of timings,
+// histograms and distributions kept for each series between two flushes.
+const maxAggregatedSamples = 1024
+
+// aggregatorKey identifies an aggregated series.
+type aggregatorKey struct {
+	stat   statType
+	stream *stream
+	rate   float32
+	attrs  attribute.Distinct
+}
+
+// aggregation holds the aggregated values of a series.
+type aggregation struct {
+	tags []statsd.Tag
+
+	// value is the sum of counters and gauge deltas, or the last value of
+	// gauges.
+	value float64
+	// samples are a uniform sample of at most maxAggregatedSamples of the
+	// count records of timings, histograms and distributions.
+	samples []float64
+	count   int
+}
+
+// addSample adds val to the samples by reservoir sampling, so that each of the
+// records has the same probability of being kept.
+func (agg *aggregation) addSample(val float64) {
+	agg.count++
+	if len(agg.samples) < maxAggregatedSamples {
+		agg.samples = append(agg.samples, val)
+		return
+	}
+	if j := int(randFloat32() * float32(agg.count)); j < len(agg.samples) {
+		agg.samples[j] = val
+	}
+}
+
+// aggregator accumulates measurements of synchronous instruments until they
+// are flushed.
+//
+// It is safe to use concurrently.
+type aggregator struct {
+	provider *MeterProvider
+
+	mu     sync.Mutex
+	series map[aggregatorKey]*aggregation
+}
+
+func newAggregator(provider *MeterProvider) *aggregator {
+	return &aggregator{
+		provider: provider,
+		series:   make(map[aggregatorKey]*aggregation),
+	}
+}
+
+// record aggregates val in the series identified by stat, s, rate and attrs.
+// Series are sent with their sample rate, so that the backend scales sampled
+// sums back up.
+func (a *aggregator) record(stat statType, s *stream, val float64, rate float32, attrs attribute.Set) {
+	key := aggregatorKey{stat: stat, stream: s, rate: rate, attrs: attrs.Equivalent()}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	agg, ok := a.series[key]
+	if !ok {
+		agg = &aggregation{tags: collectTags(a.provider, s, attrs)}
+		a.series[key] = agg
+	}
+
+	switch stat {
+	case statCount, statGaugeDelta:
+		agg.value += val
+	case statGauge:
+		agg.value = val
+	default:
+		agg.addSample(val)
+	}
+}
+
+// flush sends all aggregated series and resets the aggregator.
+func (a *aggregator) flush() {
+	a.mu.Lock()
+	series := a.series
+	a.series = make(map[aggregatorKey]*aggregation, len(series))
+	a.mu.Unlock()
+
+	for key, agg := range series {
+		switch key.stat {
+		case statCount, statGaugeDelta, statGauge:
+			a.provider.send(key.stat, key.stream.name, agg.value, key.rate, agg.tags)
+		default:
+			// The rate of the kept samples is lowered so that the
+			// backend scales the count of records back up.
+			rate := key.rate * float32(len(agg.samples)) / float32(agg.count)
+			for _, v := range agg.samples {
+				a.provider.send(key.stat, key.stream.name, v, rate, agg.tags)
+			}
+		}
+	}
+}
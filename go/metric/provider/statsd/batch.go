@@ -0,0 +1,139 @@
+package statsd
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+)
+
+// Maximum sizes of a datagram.
+const (
+	// MTUUDP is safe for UDP on local networks and most clouds.
+	MTUUDP = 1432
+	// MTUUDS is the default maximum datagram size of the Datadog agent on
+	// unix domain sockets.
+	MTUUDS = 8192
+)
+
+const defaultBatchInterval = time.Millisecond * 100
+
+var errBatchSenderClosed = errors.New("batch sender closed")
+
+// BatchSender is a Transport packing the lines it sends, separated by
+// newlines, into datagrams of at most mtu bytes. A datagram is sent when the
+// next line does not fit, or interval after its first line. A line longer
+// than mtu is sent alone.
+//
+// It is also a statsd.Sender. The sender must not retain the data it sends. It is safe to use
+// concurrently, so a client sending to it can be shared by workers.
+type BatchSender struct {
+	sender   Transport
+	mtu      int
+	interval time.Duration
+
+	mu     sync.Mutex
+	buf    []byte
+	timer  *time.Timer
+	closed bool
+}
+
+var (
+	_ Transport     = &BatchSender{}
+	_ statsd.Sender = &BatchSender{}
+)
+
+// NewBatchSender returns a BatchSender sending datagrams of at most mtu
+// bytes to sender. If mtu <= 0, MTUUDP is used. If interval <= 0, 100ms is
+// used.
+func NewBatchSender(sender Transport, mtu int, interval time.Duration) *BatchSender {
+	if mtu <= 0 {
+		mtu = MTUUDP
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	return &BatchSender{
+		sender:   sender,
+		mtu:      mtu,
+		interval: interval,
+		buf:      make([]byte, 0, mtu),
+	}
+}
+
+// Send adds data to the datagram, sending the datagram first if data does
+// not fit in it.
+func (s *BatchSender) Send(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, errBatchSenderClosed
+	}
+
+	var err error
+	if len(s.buf) > 0 && len(s.buf)+1+len(data) > s.mtu {
+		err = s.flush()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	} else if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, s.flushTimer)
+	} else {
+		s.timer.Reset(s.interval)
+	}
+	s.buf = append(s.buf, data...)
+
+	if len(s.buf) >= s.mtu {
+		if ferr := s.flush(); err == nil {
+			err = ferr
+		}
+	}
+	return len(data), err
+}
+
+// Flush sends the datagram.
+func (s *BatchSender) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush()
+}
+
+// Close sends the datagram and closes the underlying sender.
+func (s *BatchSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	err := s.flush()
+	if cerr := s.sender.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *BatchSender) flushTimer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = s.flush()
+}
+
+// flush sends the datagram. s.mu must be held.
+func (s *BatchSender) flush() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	_, err := s.sender.Send(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
@@ -0,0 +1,100 @@
+package statsd
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender records the datagrams it sends.
+type recordingSender struct {
+	mu        sync.Mutex
+	datagrams []string
+	closed    bool
+}
+
+func (s *recordingSender) Send(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.datagrams = append(s.datagrams, string(data))
+	return len(data), nil
+}
+
+func (s *recordingSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *recordingSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.datagrams...)
+}
+
+func TestBatchSender(t *testing.T) {
+	rs := &recordingSender{}
+	bs := NewBatchSender(rs, 16, time.Hour)
+
+	for _, line := range []string{"a:1|c", "b:2|c", "c:3|c", strings.Repeat("x", 20), "d:4|c"} {
+		n, err := bs.Send([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.Equal(t, []string{"a:1|c\nb:2|c", "c:3|c", strings.Repeat("x", 20)}, rs.sent())
+
+	require.NoError(t, bs.Flush())
+	assert.Equal(t, "d:4|c", rs.sent()[3])
+
+	_, err := bs.Send([]byte("e:5|c"))
+	require.NoError(t, err)
+	require.NoError(t, bs.Close())
+	assert.Equal(t, "e:5|c", rs.sent()[4])
+	assert.True(t, rs.closed)
+
+	_, err = bs.Send([]byte("f:6|c"))
+	require.ErrorIs(t, err, errBatchSenderClosed)
+}
+
+func TestBatchSenderInterval(t *testing.T) {
+	rs := &recordingSender{}
+	bs := NewBatchSender(rs, MTUUDP, time.Millisecond)
+
+	_, err := bs.Send([]byte("a:1|c"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(rs.sent()) == 1 }, time.Second, time.Millisecond)
+
+	_, err = bs.Send([]byte("b:2|c"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(rs.sent()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"a:1|c", "b:2|c"}, rs.sent())
+}
+
+func TestBatchSenderWithWorkers(t *testing.T) {
+	rs := &recordingSender{}
+	bs := NewBatchSender(rs, MTUUDP, time.Hour)
+	client, err := statsd.NewClientWithSender(bs, "", 0)
+	require.NoError(t, err)
+
+	sender := newWorkerStatSender(4, 0, BackpressureBlock, 0, NewStatSender(client))
+	require.NoError(t, sender.Start())
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, sender.Inc("hits", 1, 1.0))
+	}
+	require.NoError(t, sender.Stop())
+	require.NoError(t, bs.Flush())
+
+	lines := 0
+	for _, d := range rs.sent() {
+		assert.LessOrEqual(t, len(d), MTUUDP)
+		lines += len(strings.Split(d, "\n"))
+	}
+	assert.Equal(t, 1000, lines)
+	assert.Less(t, len(rs.sent()), 20)
+}
@@ -0,0 +1,68 @@
+package statsd
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// overflowSet is the attribute set of the measurements exceeding the
+// cardinality limit of an instrument.
+var overflowSet = attribute.NewSet(attribute.Bool("otel.metric.overflow", true))
+
+// cardinalityLimiter limits the number of distinct attribute sets of an
+// instrument until it is reset. The limit includes the overflow attribute set,
+// so limit-1 sets are kept as is.
+//
+// It is safe to use concurrently.
+type cardinalityLimiter struct {
+	provider *MeterProvider
+	name     string
+	limit    int
+
+	mu       sync.Mutex
+	seen     map[attribute.Distinct]struct{}
+	reported bool
+}
+
+func newCardinalityLimiter(provider *MeterProvider, name string, limit int) *cardinalityLimiter {
+	return &cardinalityLimiter{
+		provider: provider,
+		name:     name,
+		limit:    limit,
+		seen:     make(map[attribute.Distinct]struct{}),
+	}
+}
+
+// attributes returns attrs, or the overflow attribute set if attrs is a new
+// set and the limit is reached.
+func (l *cardinalityLimiter) attributes(attrs attribute.Set) attribute.Set {
+	key := attrs.Equivalent()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seen[key]; ok {
+		return attrs
+	}
+	if len(l.seen) < l.limit-1 {
+		l.seen[key] = struct{}{}
+		return attrs
+	}
+
+	l.provider.overflows.Add(1)
+	if !l.reported {
+		l.reported = true
+		otel.Handle(fmt.Errorf("cardinality limit of %d reached by instrument %q: measurements are folded into the otel.metric.overflow attribute set", l.limit, l.name))
+	}
+	return overflowSet
+}
+
+// reset forgets the attribute sets seen, so that new sets are kept again.
+func (l *cardinalityLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.seen)
+}
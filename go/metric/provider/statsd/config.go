@@ -1,10 +1,12 @@
 package statsd
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/cactus/go-statsd-client/v5/statsd"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
@@ -14,17 +16,96 @@ type config struct {
 	// created by the MeterProvider.
 	Resource *resource.Resource
 
-	// Statsd client to use
+	// Statsd client to use instead of the transport
 	StatsdClient statsd.StatSender
 
+	// Transport of the encoded stats. Default is the transport of Endpoint
+	Transport Transport
+
+	// Endpoint of the default transport. Default is 127.0.0.1:8125
+	Endpoint string
+
+	// TLS configuration of the default transport with a tcp:// endpoint
+	TLSConfig *tls.Config
+
+	// Called when the default transport with a tcp:// endpoint connects or disconnects
+	ConnectionStateCallback func(ConnectionState, error)
+
+	// Format of the tags written by the encoder. Default is TagFormatDogStatsD
+	TagFormat TagFormat
+
 	// Number of Workers. If <= 0, send synchronously
 	Workers int
 
 	// Size of the worker chan buffer. Default is workers * 10
 	WorkerChanBufferSize int
 
+	// What the workers do when their chan buffer is full. Default is BackpressureBlock
+	Backpressure BackpressurePolicy
+
+	// Maximum time to wait with BackpressureBlockTimeout
+	BackpressureTimeout time.Duration
+
+	// Maximum size of the datagrams sent to the transport. No batching if <= 0
+	BatchSize int
+
+	// Maximum time a stat waits in a batch
+	BatchInterval time.Duration
+
 	// Intervening time to call observables
 	Interval time.Duration
+
+	// How up-down counters are sent. Default is UpDownCounterModeDelta
+	UpDownCounterMode UpDownCounterMode
+
+	// How observable up-down counters are sent. Default is UpDownCounterModeAbsolute
+	ObservableUpDownCounterMode UpDownCounterMode
+
+	// StatsD type of histograms. Default is HistogramTypeTiming
+	HistogramType HistogramType
+
+	// StatsD type of histograms by instrument name, overriding HistogramType
+	InstrumentHistogramTypes map[string]HistogramType
+
+	// Aggregate synchronous instruments and send them once per Interval
+	Aggregation bool
+
+	// Sample rate of synchronous instruments. Default is 1.0
+	SampleRate float32
+
+	// Sample rate by instrument name, overriding SampleRate
+	InstrumentSampleRates map[string]float32
+
+	// Prefix of all metric names
+	Prefix string
+
+	// How the instrumentation scope is added to metrics. Default is ScopeModeNone
+	ScopeMode ScopeMode
+
+	// Add the instrumentation scope version along with its name
+	ScopeVersion bool
+
+	// Sanitizer of metric names and tags. Default is NewDefaultSanitizer()
+	Sanitizer Sanitizer
+
+	// Do not send resource attributes as tags
+	DisableResourceTags bool
+
+	// Filters of the resource attributes sent as tags. An attribute is sent
+	// if all filters keep it
+	ResourceTagFilters []attribute.Filter
+
+	// Tag keys of resource attributes, by attribute key
+	ResourceTagRenames map[attribute.Key]string
+
+	// Views overriding how instruments are sent
+	Views []View
+
+	// Maximum number of attribute sets of each synchronous instrument. No limit if <= 0
+	CardinalityLimit int
+
+	// Additional destinations of the stats
+	Destinations []Destination
 }
 
 // Option is the interface that applies the value to a configuration option.
@@ -50,7 +131,17 @@ func (o resourceOption) apply(cfg config) config {
 	return cfg
 }
 
-// WithStatsdClient sets the StatsD client. If not set, a default one will be created.
+// WithStatsdClient sets the StatsD client, adapted to send the stats instead
+// of the encoder and the transport. Clients of the statsd.StatSender
+// interface cannot send float values natively, see NewStatSender.
+// Measurements are sampled by the MeterProvider, so clients with a
+// NewSubStatter method, such as *statsd.Client, are copied with it and the copy
+// is set to send every measurement it receives; s itself is left unchanged.
+// Other clients must not sample the measurements again.
+// Clients with a Flush() error method are flushed by ForceFlush and Shutdown.
+// The buffered clients of github.com/cactus/go-statsd-client (UseBuffered) do
+// not have one and are not flushed: use WithBatching instead where the stats
+// must be sent by ForceFlush, such as in AWS Lambda.
 func WithStatsdClient(s statsd.StatSender) Option {
 	return statsdclientOption{s}
 }
@@ -62,6 +153,78 @@ func (o statsdclientOption) apply(cfg config) config {
 	return cfg
 }
 
+// WithTransport sets the transport of the encoded stats. It is not closed by
+// Shutdown. Default is the transport of the endpoint set with WithEndpoint.
+func WithTransport(t Transport) Option {
+	return transportOption{t}
+}
+
+type transportOption struct{ t Transport }
+
+func (o transportOption) apply(cfg config) config {
+	cfg.Transport = o.t
+	return cfg
+}
+
+// WithEndpoint sets the endpoint of the transport created by the
+// MeterProvider, as accepted by NewTransport: "host:port" or "udp://host:port"
+// for UDP, "tcp://host:port" for TCP, "unix:///path/to/socket" or
+// "unixgram:///path/to/socket" for unix domain sockets. It is ignored with WithTransport or WithStatsdClient.
+// Default is 127.0.0.1:8125.
+func WithEndpoint(endpoint string) Option {
+	return endpointOption{endpoint}
+}
+
+type endpointOption struct{ endpoint string }
+
+func (o endpointOption) apply(cfg config) config {
+	cfg.Endpoint = o.endpoint
+	return cfg
+}
+
+// WithTLSConfig enables TLS with cfg for the transport created by the
+// MeterProvider with a "tcp://host:port" endpoint.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return tlsConfigOption{cfg}
+}
+
+type tlsConfigOption struct{ cfg *tls.Config }
+
+func (o tlsConfigOption) apply(cfg config) config {
+	cfg.TLSConfig = o.cfg
+	return cfg
+}
+
+// WithConnectionStateCallback sets a function called when the transport
+// created by the MeterProvider with a "tcp://host:port" endpoint connects or
+// disconnects. It must not block.
+func WithConnectionStateCallback(f func(ConnectionState, error)) Option {
+	return connectionStateCallbackOption{f}
+}
+
+type connectionStateCallbackOption struct {
+	f func(ConnectionState, error)
+}
+
+func (o connectionStateCallbackOption) apply(cfg config) config {
+	cfg.ConnectionStateCallback = o.f
+	return cfg
+}
+
+// WithTagFormat sets how the encoder writes tags. It is ignored with
+// WithStatsdClient, whose client has its own format. Default is
+// TagFormatDogStatsD.
+func WithTagFormat(f TagFormat) Option {
+	return tagFormatOption{f}
+}
+
+type tagFormatOption struct{ f TagFormat }
+
+func (o tagFormatOption) apply(cfg config) config {
+	cfg.TagFormat = o.f
+	return cfg
+}
+
 // WithWorkers sets the number of Workers. If <= 0, send synchronously
 func WithWorkers(workers int) Option {
 	return workersOption{workers}
@@ -86,6 +249,77 @@ func (o workerChanBufferSizeOption) apply(cfg config) config {
 	return cfg
 }
 
+// BackpressurePolicy is what the workers do with a stat when their chan buffer
+// is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock blocks the caller until the stat is queued.
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDropNewest drops the stat.
+	BackpressureDropNewest
+	// BackpressureDropOldest drops the oldest queued stat to queue the stat.
+	BackpressureDropOldest
+	// BackpressureBlockTimeout blocks the caller until the stat is queued,
+	// or drops the stat after the backpressure timeout.
+	BackpressureBlockTimeout
+)
+
+// WithBackpressure sets what the workers do with a stat when their chan
+// buffer is full. Dropped stats are counted in Stats. Default is
+// BackpressureBlock.
+func WithBackpressure(policy BackpressurePolicy) Option {
+	return backpressureOption{policy}
+}
+
+type backpressureOption struct{ policy BackpressurePolicy }
+
+func (o backpressureOption) apply(cfg config) config {
+	cfg.Backpressure = o.policy
+	return cfg
+}
+
+// WithBackpressureTimeout sets the maximum time to wait for the workers with
+// BackpressureBlockTimeout. Default is 10ms.
+func WithBackpressureTimeout(timeout time.Duration) Option {
+	return backpressureTimeoutOption{timeout}
+}
+
+type backpressureTimeoutOption struct{ timeout time.Duration }
+
+func (o backpressureTimeoutOption) apply(cfg config) config {
+	cfg.BackpressureTimeout = o.timeout
+	return cfg
+}
+
+// WithBatching packs the stats sent to the transport into datagrams of at
+// most mtu bytes, like MTUUDP or MTUUDS, instead of sending a datagram per
+// stat. It is ignored with WithStatsdClient, whose client can send to a
+// BatchSender.
+func WithBatching(mtu int) Option {
+	return batchingOption{mtu}
+}
+
+type batchingOption struct{ mtu int }
+
+func (o batchingOption) apply(cfg config) config {
+	cfg.BatchSize = o.mtu
+	return cfg
+}
+
+// WithBatchInterval sets the maximum time a stat waits in a batch before the
+// batch is sent. Default is 100ms.
+func WithBatchInterval(d time.Duration) Option {
+	return batchIntervalOption{d}
+}
+
+type batchIntervalOption struct{ d time.Duration }
+
+func (o batchIntervalOption) apply(cfg config) config {
+	cfg.BatchInterval = o.d
+	return cfg
+}
+
 // WithInterval sets the intervening time to call observables
 func WithInterval(d time.Duration) Option {
 	return intervalOption{d}
@@ -97,3 +331,348 @@ func (o intervalOption) apply(cfg config) config {
 	cfg.Interval = o.interval
 	return cfg
 }
+
+// UpDownCounterMode defines how up-down counters are sent to StatsD.
+type UpDownCounterMode int
+
+const (
+	// UpDownCounterModeDelta sends each Add as a gauge delta (+N|g or -N|g).
+	UpDownCounterModeDelta UpDownCounterMode = iota
+	// UpDownCounterModeAbsolute keeps the running total locally and sends it
+	// as an absolute gauge (N|g).
+	UpDownCounterModeAbsolute
+)
+
+// WithUpDownCounterMode sets how up-down counters are sent. Default is UpDownCounterModeDelta
+func WithUpDownCounterMode(mode UpDownCounterMode) Option {
+	return upDownCounterModeOption{mode}
+}
+
+type upDownCounterModeOption struct{ mode UpDownCounterMode }
+
+func (o upDownCounterModeOption) apply(cfg config) config {
+	cfg.UpDownCounterMode = o.mode
+	return cfg
+}
+
+// WithObservableUpDownCounterMode sets how observable up-down counters are sent. Default is UpDownCounterModeAbsolute
+func WithObservableUpDownCounterMode(mode UpDownCounterMode) Option {
+	return observableUpDownCounterModeOption{mode}
+}
+
+type observableUpDownCounterModeOption struct{ mode UpDownCounterMode }
+
+func (o observableUpDownCounterModeOption) apply(cfg config) config {
+	cfg.ObservableUpDownCounterMode = o.mode
+	return cfg
+}
+
+// HistogramType defines the StatsD type histograms are sent as.
+type HistogramType int
+
+const (
+	// HistogramTypeTiming sends histograms as timings (|ms). Values of
+	// instruments with a duration unit (ns, us, ms, s, min, h) are converted
+	// to milliseconds.
+	HistogramTypeTiming HistogramType = iota + 1
+	// HistogramTypeHistogram sends histograms as histograms (|h).
+	HistogramTypeHistogram
+	// HistogramTypeDistribution sends histograms as DogStatsD distributions
+	// (|d).
+	HistogramTypeDistribution
+	// HistogramTypeAuto sends histograms with a duration unit as timings, and
+	// the others as histograms.
+	HistogramTypeAuto
+)
+
+// statType returns the StatsD type histograms of type t are sent as.
+func (t HistogramType) statType() statType {
+	switch t {
+	case HistogramTypeHistogram:
+		return statHistogram
+	case HistogramTypeDistribution:
+		return statDistribution
+	}
+	return statTiming
+}
+
+// WithHistogramType sets the StatsD type of histograms. Default is HistogramTypeTiming
+func WithHistogramType(t HistogramType) Option {
+	return histogramTypeOption{t}
+}
+
+type histogramTypeOption struct{ t HistogramType }
+
+func (o histogramTypeOption) apply(cfg config) config {
+	cfg.HistogramType = o.t
+	return cfg
+}
+
+// WithInstrumentHistogramType sets the StatsD type of the histogram named name,
+// overriding WithHistogramType.
+func WithInstrumentHistogramType(name string, t HistogramType) Option {
+	return instrumentHistogramTypeOption{name, t}
+}
+
+type instrumentHistogramTypeOption struct {
+	name string
+	t    HistogramType
+}
+
+func (o instrumentHistogramTypeOption) apply(cfg config) config {
+	types := make(map[string]HistogramType, len(cfg.InstrumentHistogramTypes)+1)
+	for k, v := range cfg.InstrumentHistogramTypes {
+		types[k] = v
+	}
+	types[o.name] = o.t
+	cfg.InstrumentHistogramTypes = types
+	return cfg
+}
+
+// WithAggregation enables client-side aggregation of synchronous instruments.
+// Counters are summed, gauges keep their last value, and histogram records
+// are merged for each instrument and attribute set, then sent once per
+// interval instead of on every measurement.
+//
+// At most 1024 histogram records are kept for each instrument and attribute
+// set per interval. Beyond that, a uniform sample of 1024 records is sent
+// with a sample rate lowered accordingly, so that the backend still counts
+// every record.
+func WithAggregation(enabled bool) Option {
+	return aggregationOption{enabled}
+}
+
+type aggregationOption struct{ enabled bool }
+
+func (o aggregationOption) apply(cfg config) config {
+	cfg.Aggregation = o.enabled
+	return cfg
+}
+
+// WithSampleRate sets the sample rate of synchronous instruments, between 0.0
+// and 1.0. Measurements are randomly dropped by the MeterProvider and sent
+// with the rate (|@rate) so that the backend scales counts back up. Gauges
+// and up-down counters are never sampled, as StatsD servers ignore the rate of
+// gauges. A rate outside (0, 1] is reported with otel.Handle and ignored.
+// Default is 1.0
+func WithSampleRate(rate float32) Option {
+	return sampleRateOption{rate}
+}
+
+type sampleRateOption struct{ rate float32 }
+
+func (o sampleRateOption) apply(cfg config) config {
+	if validSampleRate(o.rate) {
+		cfg.SampleRate = o.rate
+	}
+	return cfg
+}
+
+// WithInstrumentSampleRate sets the sample rate of the synchronous instrument
+// named name, overriding WithSampleRate. A rate outside (0, 1] is reported
+// with otel.Handle and ignored.
+func WithInstrumentSampleRate(name string, rate float32) Option {
+	return instrumentSampleRateOption{name, rate}
+}
+
+type instrumentSampleRateOption struct {
+	name string
+	rate float32
+}
+
+func (o instrumentSampleRateOption) apply(cfg config) config {
+	if !validSampleRate(o.rate) {
+		return cfg
+	}
+	rates := make(map[string]float32, len(cfg.InstrumentSampleRates)+1)
+	for k, v := range cfg.InstrumentSampleRates {
+		rates[k] = v
+	}
+	rates[o.name] = o.rate
+	cfg.InstrumentSampleRates = rates
+	return cfg
+}
+
+// WithPrefix sets the prefix of all metric names, joined to them with a dot.
+func WithPrefix(prefix string) Option {
+	return prefixOption{prefix}
+}
+
+type prefixOption struct{ prefix string }
+
+func (o prefixOption) apply(cfg config) config {
+	cfg.Prefix = o.prefix
+	return cfg
+}
+
+// ScopeMode defines how the instrumentation scope of a Meter is added to the
+// metrics of its instruments.
+type ScopeMode int
+
+const (
+	// ScopeModeNone does not add the instrumentation scope.
+	ScopeModeNone ScopeMode = iota
+	// ScopeModePrefix prefixes metric names with the scope name, after the
+	// prefix set with WithPrefix.
+	ScopeModePrefix
+	// ScopeModeTags adds the scope name as the otel.scope.name tag.
+	ScopeModeTags
+)
+
+const (
+	scopeNameTag    = "otel.scope.name"
+	scopeVersionTag = "otel.scope.version"
+)
+
+// WithScopeMode sets how the instrumentation scope is added to metrics. Default is ScopeModeNone
+func WithScopeMode(mode ScopeMode) Option {
+	return scopeModeOption{mode}
+}
+
+type scopeModeOption struct{ mode ScopeMode }
+
+func (o scopeModeOption) apply(cfg config) config {
+	cfg.ScopeMode = o.mode
+	return cfg
+}
+
+// WithScopeVersion adds the instrumentation scope version along with its
+// name: to the prefix, with dots replaced by underscores, or as the
+// otel.scope.version tag.
+func WithScopeVersion(enabled bool) Option {
+	return scopeVersionOption{enabled}
+}
+
+type scopeVersionOption struct{ enabled bool }
+
+func (o scopeVersionOption) apply(cfg config) config {
+	cfg.ScopeVersion = o.enabled
+	return cfg
+}
+
+// WithSanitizer sets the Sanitizer of metric names and tags. A nil Sanitizer
+// disables sanitization. Default is NewDefaultSanitizer()
+func WithSanitizer(s Sanitizer) Option {
+	return sanitizerOption{s}
+}
+
+type sanitizerOption struct{ Sanitizer }
+
+func (o sanitizerOption) apply(cfg config) config {
+	cfg.Sanitizer = o.Sanitizer
+	return cfg
+}
+
+// WithResourceTags sets whether resource attributes are sent as tags. Default is true
+func WithResourceTags(enabled bool) Option {
+	return resourceTagsOption{enabled}
+}
+
+type resourceTagsOption struct{ enabled bool }
+
+func (o resourceTagsOption) apply(cfg config) config {
+	cfg.DisableResourceTags = !o.enabled
+	return cfg
+}
+
+// WithResourceTagFilter adds a filter of the resource attributes sent as
+// tags. An attribute is sent if all filters keep it.
+func WithResourceTagFilter(f attribute.Filter) Option {
+	return resourceTagFilterOption{f}
+}
+
+// WithResourceTagAllowlist only sends the resource attributes with one of the
+// keys as tags.
+func WithResourceTagAllowlist(keys ...attribute.Key) Option {
+	return resourceTagFilterOption{attribute.NewAllowKeysFilter(keys...)}
+}
+
+// WithResourceTagDenylist does not send the resource attributes with one of
+// the keys as tags.
+func WithResourceTagDenylist(keys ...attribute.Key) Option {
+	return resourceTagFilterOption{attribute.NewDenyKeysFilter(keys...)}
+}
+
+type resourceTagFilterOption struct{ filter attribute.Filter }
+
+func (o resourceTagFilterOption) apply(cfg config) config {
+	filters := make([]attribute.Filter, 0, len(cfg.ResourceTagFilters)+1)
+	filters = append(filters, cfg.ResourceTagFilters...)
+	cfg.ResourceTagFilters = append(filters, o.filter)
+	return cfg
+}
+
+// WithResourceTagRename sends the resource attribute with the key from as
+// the tag named to.
+func WithResourceTagRename(from attribute.Key, to string) Option {
+	return resourceTagRenameOption{from, to}
+}
+
+type resourceTagRenameOption struct {
+	from attribute.Key
+	to   string
+}
+
+func (o resourceTagRenameOption) apply(cfg config) config {
+	renames := make(map[attribute.Key]string, len(cfg.ResourceTagRenames)+1)
+	for k, v := range cfg.ResourceTagRenames {
+		renames[k] = v
+	}
+	renames[o.from] = o.to
+	cfg.ResourceTagRenames = renames
+	return cfg
+}
+
+// WithView adds views overriding how instruments are sent. The first view
+// matching an instrument is used.
+func WithView(views ...View) Option {
+	return viewOption{views}
+}
+
+type viewOption struct{ views []View }
+
+func (o viewOption) apply(cfg config) config {
+	views := make([]View, 0, len(cfg.Views)+len(o.views))
+	views = append(views, cfg.Views...)
+	cfg.Views = append(views, o.views...)
+	return cfg
+}
+
+// WithCardinalityLimit sets the maximum number of attribute sets of each
+// synchronous instrument per collection interval, including the overflow set.
+// Once the limit is reached, measurements with a new attribute set are folded
+// into the set made of the otel.metric.overflow=true attribute until the next
+// collection, as the stats are sent as deltas. Observable instruments are
+// not limited, as their attribute sets are bounded by their callbacks. No
+// limit if <= 0, which is the default.
+func WithCardinalityLimit(limit int) Option {
+	return cardinalityLimitOption{limit}
+}
+
+type cardinalityLimitOption struct{ limit int }
+
+func (o cardinalityLimitOption) apply(cfg config) config {
+	cfg.CardinalityLimit = o.limit
+	return cfg
+}
+
+// WithDestination adds destinations the stats are sent to, in addition to
+// the StatsD client or transport of the MeterProvider. The workers and the
+// batch interval of the MeterProvider apply to every destination.
+//
+// So that a destination failing or hanging does not block the others, each
+// destination, including the one of the MeterProvider, then sends the stats
+// with its own workers, at least one, started right away. Their chan buffer
+// holds 1000 stats unless set with WithWorkerChanBufferSize, and the stats are
+// dropped when it is full: BackpressureBlock and BackpressureBlockTimeout are
+// replaced by BackpressureDropNewest.
+func WithDestination(destinations ...Destination) Option {
+	return destinationOption{destinations}
+}
+
+type destinationOption struct{ destinations []Destination }
+
+func (o destinationOption) apply(cfg config) config {
+	cfg.Destinations = append(cfg.Destinations[:len(cfg.Destinations):len(cfg.Destinations)], o.destinations...)
+	return cfg
+}
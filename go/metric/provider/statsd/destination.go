@@ -0,0 +1,209 @@
+package statsd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"go.opentelemetry.io/otel"
+)
+
+// Destination is an additional destination of the stats, set with
+// WithDestination. Each destination has its own transport, workers and
+// batching, so a failing destination does not keep the stats from being
+// sent to the others.
+type Destination struct {
+	// Transport of the stats. Default is the transport of Endpoint.
+	Transport Transport
+	// Endpoint of the transport, as accepted by WithEndpoint.
+	Endpoint string
+	// TLSConfig enables TLS with a tcp:// endpoint.
+	TLSConfig *tls.Config
+	// TagFormat is how the tags are written. Default is TagFormatDogStatsD.
+	TagFormat TagFormat
+	// Prefix is prepended to the names of the stats, after the prefix of
+	// the MeterProvider.
+	Prefix string
+	// MaxPacketSize enables batching with the maximum datagram size.
+	MaxPacketSize int
+	// NameFilter returns whether the stat named name is sent, including the
+	// prefix of the MeterProvider. All stats are sent if it is nil.
+	NameFilter func(name string) bool
+	// TagFilter returns whether the tag with key is sent. All tags are sent
+	// if it is nil.
+	TagFilter func(key string) bool
+}
+
+// defaultDestinationBufferSize is the size of the worker chan buffer of each
+// destination if there are several of them.
+const defaultDestinationBufferSize = 1000
+
+// destination sends the stats to a StatsD client or a transport.
+type destination struct {
+	sender StatSender
+	// workers is the sender if the stats are sent by workers.
+	workers *workerStatSender
+
+	// client is the client the sender sends to if set with
+	// WithStatsdClient, flushed if it implements flusher.
+	client statsd.StatSender
+	// transport is the transport the sender sends to otherwise, closed on
+	// shutdown if it was created by the provider.
+	transport     Transport
+	ownsTransport bool
+	// batch is the transport or wraps it if batching is enabled.
+	batch *BatchSender
+	// base is the transport wrapped by batch, or the transport.
+	base Transport
+
+	prefix     string
+	nameFilter func(string) bool
+	tagFilter  func(string) bool
+}
+
+// newClientDestination returns a destination sending to client.
+func newClientDestination(client statsd.StatSender) *destination {
+	return &destination{
+		sender: NewStatSender(presampledClient(client)),
+		client: client,
+	}
+}
+
+// newTransportDestination returns a destination encoding the stats in
+// format and sending them to transport, or else to the transport of endpoint
+// created with tcpOpts.
+func newTransportDestination(transport Transport, endpoint string, tcpOpts []TCPOption, format TagFormat, batchSize int, batchInterval time.Duration) *destination {
+	ret := &destination{
+		transport:     transport,
+		ownsTransport: transport == nil,
+	}
+	if ret.transport == nil {
+		var err error
+		ret.transport, err = NewTransport(endpoint, tcpOpts...)
+		if err != nil {
+			otel.Handle(err)
+			ret.transport = errTransport{err}
+		}
+	}
+	ret.base = ret.transport
+	if batchSize > 0 {
+		ret.batch = NewBatchSender(ret.transport, batchSize, batchInterval)
+		ret.transport = ret.batch
+	}
+	ret.sender = NewTransportStatSender(ret.transport, format)
+	return ret
+}
+
+// newDestination returns the destination of d.
+func newDestination(d Destination, c config) *destination {
+	var tcpOpts []TCPOption
+	if d.TLSConfig != nil {
+		tcpOpts = append(tcpOpts, WithTCPTLSConfig(d.TLSConfig))
+	}
+	ret := newTransportDestination(d.Transport, d.Endpoint, tcpOpts, d.TagFormat, d.MaxPacketSize, c.BatchInterval)
+	ret.prefix = strings.TrimSuffix(d.Prefix, ".")
+	ret.nameFilter = d.NameFilter
+	ret.tagFilter = d.TagFilter
+	return ret
+}
+
+// withWorkers makes the destination send the stats with workers. If there
+// are several destinations, each one has at least a worker and drops the
+// stats when its chan buffer is full, so that it does not block the others.
+func (d *destination) withWorkers(c config) {
+	workers, bufferSize, backpressure := c.Workers, c.WorkerChanBufferSize, c.Backpressure
+	if len(c.Destinations) > 0 {
+		workers = max(workers, 1)
+		if bufferSize <= 0 {
+			bufferSize = defaultDestinationBufferSize
+		}
+		if backpressure == BackpressureBlock || backpressure == BackpressureBlockTimeout {
+			backpressure = BackpressureDropNewest
+		}
+	}
+	d.workers = newWorkerStatSender(workers, bufferSize, backpressure, c.BackpressureTimeout, d.sender)
+	d.sender = d.workers
+}
+
+// send sends val as a metric of type stat, if the destination accepts it.
+func (d *destination) send(stat statType, name string, val float64, rate float32, tags []statsd.Tag) error {
+	if d.nameFilter != nil && !d.nameFilter(name) {
+		return nil
+	}
+	if d.prefix != "" {
+		name = d.prefix + "." + name
+	}
+	if d.tagFilter != nil {
+		filtered := make([]statsd.Tag, 0, len(tags))
+		for _, tag := range tags {
+			if d.tagFilter(tag[0]) {
+				filtered = append(filtered, tag)
+			}
+		}
+		tags = filtered
+	}
+	return sendStat(d.sender, stat, name, val, rate, tags...)
+}
+
+// start starts the workers, if any and not running already.
+func (d *destination) start() error {
+	if d.workers == nil {
+		return nil
+	}
+	if err := d.workers.Start(); !errors.Is(err, errWorkersStarted) {
+		return err
+	}
+	return nil
+}
+
+// stop stops the workers, if any, dropping the queued stats if ctx is done
+// before they are sent.
+func (d *destination) stop(ctx context.Context) error {
+	if d.workers == nil {
+		return nil
+	}
+	return d.workers.stopContext(ctx)
+}
+
+// flush waits for the workers to send the queued stats, then flushes the
+// client or the transport.
+func (d *destination) flush(ctx context.Context) error {
+	if d.workers != nil {
+		if err := d.workers.wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if f, ok := d.client.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	if d.batch != nil {
+		if err := d.batch.Flush(); err != nil {
+			return err
+		}
+	}
+	switch f := d.base.(type) {
+	case contextFlusher:
+		if err := f.FlushContext(ctx); err != nil {
+			return err
+		}
+	case flusher:
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close closes the transport if it was created by the provider.
+func (d *destination) close() error {
+	if !d.ownsTransport || d.transport == nil {
+		return nil
+	}
+	return d.transport.Close()
+}
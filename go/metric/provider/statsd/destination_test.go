@@ -0,0 +1,128 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestProviderDestinations(t *testing.T) {
+	testCases := []struct {
+		name    string
+		workers int
+	}{
+		{name: "Sync"},
+		{name: "Workers", workers: 2},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			dogstatsd := &recordingSender{}
+			graphite := &recordingSender{}
+			mp := NewMeterProvider(
+				WithResource(resource.Empty()),
+				WithPrefix("app"),
+				WithWorkers(tt.workers),
+				WithTransport(dogstatsd),
+				WithDestination(
+					Destination{Transport: errTransport{errors.New("unreachable")}},
+					Destination{
+						Transport:  graphite,
+						TagFormat:  TagFormatGraphite,
+						Prefix:     "legacy.",
+						NameFilter: func(name string) bool { return !strings.HasSuffix(name, ".internal") },
+						TagFilter:  func(key string) bool { return key != "user_id" },
+					},
+				),
+			)
+			require.NoError(t, mp.Start(ctx))
+
+			m := mp.Meter("")
+			ctr, err := m.Int64Counter("requests")
+			require.NoError(t, err)
+			ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("code", "200"), attribute.String("user_id", "1")))
+			internal, err := m.Int64Counter("internal")
+			require.NoError(t, err)
+			internal.Add(ctx, 2)
+
+			require.NoError(t, mp.Shutdown(ctx))
+
+			assert.ElementsMatch(t, []string{"app.requests:1|c|#code:200,user_id:1", "app.internal:2|c"}, dogstatsd.sent())
+			assert.Equal(t, []string{"legacy.app.requests;code=200:1|c"}, graphite.sent())
+		})
+	}
+}
+
+// hangingTransport blocks sending until release is closed.
+type hangingTransport struct {
+	recordingSender
+	release chan struct{}
+}
+
+func (t *hangingTransport) Send(data []byte) (int, error) {
+	<-t.release
+	return t.recordingSender.Send(data)
+}
+
+func TestProviderHangingDestination(t *testing.T) {
+	testCases := []struct {
+		name           string
+		hangingPrimary bool
+	}{
+		{name: "Primary", hangingPrimary: true},
+		{name: "Destination"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			hanging := &hangingTransport{release: make(chan struct{})}
+			defer close(hanging.release)
+			healthy := &recordingSender{}
+
+			primary, other := Transport(healthy), Transport(hanging)
+			if tt.hangingPrimary {
+				primary, other = other, primary
+			}
+			mp := NewMeterProvider(
+				WithResource(resource.Empty()),
+				WithTransport(primary),
+				WithDestination(Destination{Transport: other}),
+			)
+			ctr, err := mp.Meter("").Int64Counter("hits")
+			require.NoError(t, err)
+
+			// Recording does not block, and the healthy destination
+			// receives every stat.
+			recorded := make(chan struct{})
+			go func() {
+				defer close(recorded)
+				for i := 0; i < 100; i++ {
+					ctr.Add(context.Background(), 1)
+				}
+			}()
+			select {
+			case <-recorded:
+			case <-time.After(time.Second):
+				t.Fatal("recording blocked by the hanging destination")
+			}
+
+			assert.Eventually(t, func() bool {
+				return len(healthy.sent()) == 100
+			}, time.Second, 10*time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			require.ErrorIs(t, mp.Shutdown(ctx), context.DeadlineExceeded)
+		})
+	}
+}
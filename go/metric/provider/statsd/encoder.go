@@ -0,0 +1,233 @@
+package statsd
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+)
+
+// TagFormat is how an Encoder writes the tags of a stat.
+type TagFormat int
+
+const (
+	// TagFormatDogStatsD appends the tags to the line, as in
+	// name:1|c|#key:value,key2:value2. It is the format of the Datadog agent
+	// and the OpenTelemetry Collector StatsD receiver.
+	TagFormatDogStatsD TagFormat = iota + 1
+	// TagFormatInflux appends the tags to the name, as in
+	// name,key=value,key2=value2:1|c.
+	TagFormatInflux
+	// TagFormatGraphite appends the tags to the name, as in
+	// name;key=value;key2=value2:1|c.
+	TagFormatGraphite
+	// TagFormatEtsy drops the tags, as the original Etsy StatsD does not
+	// support them.
+	TagFormatEtsy
+)
+
+// Encoder encodes stats in the StatsD line protocol. The zero value uses
+// TagFormatDogStatsD.
+type Encoder struct {
+	format TagFormat
+}
+
+// NewEncoder returns an Encoder writing tags in format.
+func NewEncoder(format TagFormat) Encoder {
+	return Encoder{format: format}
+}
+
+// Append appends to dst the line of the stat name with value, of the StatsD
+// type typ like "c", "g", "ms", "h" or "d", sampled at rate.
+func (e Encoder) Append(dst []byte, name string, value float64, typ string, rate float32, tags ...statsd.Tag) []byte {
+	dst = e.appendName(dst, name, tags)
+	dst = strconv.AppendFloat(dst, value, 'f', -1, 64)
+	return e.appendType(dst, typ, rate, tags)
+}
+
+// AppendDelta is like Append, but always writes the sign of value, as gauge
+// deltas require.
+func (e Encoder) AppendDelta(dst []byte, name string, value float64, typ string, rate float32, tags ...statsd.Tag) []byte {
+	dst = e.appendName(dst, name, tags)
+	if value >= 0 {
+		dst = append(dst, '+')
+	}
+	dst = strconv.AppendFloat(dst, value, 'f', -1, 64)
+	return e.appendType(dst, typ, rate, tags)
+}
+
+// AppendRaw appends to dst the line of the stat name with value, which
+// already includes the StatsD type, as in "1|c".
+func (e Encoder) AppendRaw(dst []byte, name string, value string, rate float32, tags ...statsd.Tag) []byte {
+	dst = e.appendName(dst, name, tags)
+	dst = append(dst, value...)
+	return e.appendType(dst, "", rate, tags)
+}
+
+// appendInt is like Append with an integer value.
+func (e Encoder) appendInt(dst []byte, name string, value int64, sign bool, typ string, rate float32, tags []statsd.Tag) []byte {
+	dst = e.appendName(dst, name, tags)
+	if sign && value >= 0 {
+		dst = append(dst, '+')
+	}
+	dst = strconv.AppendInt(dst, value, 10)
+	return e.appendType(dst, typ, rate, tags)
+}
+
+// appendName appends the name, the tags of the formats tagging names, and
+// the ':' separating the value.
+func (e Encoder) appendName(dst []byte, name string, tags []statsd.Tag) []byte {
+	dst = append(dst, name...)
+	switch e.format {
+	case TagFormatInflux:
+		dst = appendTags(dst, tags, ',', ',', '=')
+	case TagFormatGraphite:
+		dst = appendTags(dst, tags, ';', ';', '=')
+	}
+	return append(dst, ':')
+}
+
+// appendType appends the type, the sample rate if < 1, and the tags of the
+// formats tagging lines.
+func (e Encoder) appendType(dst []byte, typ string, rate float32, tags []statsd.Tag) []byte {
+	if typ != "" {
+		dst = append(dst, '|')
+		dst = append(dst, typ...)
+	}
+	if rate < 1 {
+		dst = append(dst, "|@"...)
+		dst = strconv.AppendFloat(dst, float64(rate), 'f', -1, 32)
+	}
+	if e.format == TagFormatDogStatsD || e.format == 0 {
+		if len(tags) > 0 {
+			dst = append(dst, "|#"...)
+			dst = appendTags(dst, tags, 0, ',', ':')
+		}
+	}
+	return dst
+}
+
+// appendTags appends tags, preceded by start unless it is 0, separated by
+// sep, with keys and values separated by kv.
+func appendTags(dst []byte, tags []statsd.Tag, start, sep, kv byte) []byte {
+	for i, tag := range tags {
+		if i > 0 {
+			dst = append(dst, sep)
+		} else if start != 0 {
+			dst = append(dst, start)
+		}
+		dst = append(dst, tag[0]...)
+		dst = append(dst, kv)
+		dst = append(dst, tag[1]...)
+	}
+	return dst
+}
+
+// transportStatSender encodes stats with an Encoder and sends each line to a
+// Transport.
+type transportStatSender struct {
+	transport Transport
+	encoder   Encoder
+	buffers   sync.Pool
+}
+
+var _ StatSender = &transportStatSender{}
+
+// NewTransportStatSender returns a StatSender encoding stats with tags in
+// format and sending them to transport, without depending on a StatsD
+// client. Unlike clients of the statsd.StatSender interface, it sends float
+// values, histograms and distributions natively.
+func NewTransportStatSender(transport Transport, format TagFormat) StatSender {
+	return &transportStatSender{
+		transport: transport,
+		encoder:   NewEncoder(format),
+		buffers: sync.Pool{New: func() any {
+			b := make([]byte, 0, 128)
+			return &b
+		}},
+	}
+}
+
+// send sends line, encoded in the pooled buf, and puts buf back in the pool.
+func (s *transportStatSender) send(line []byte, buf *[]byte) error {
+	_, err := s.transport.Send(line)
+	*buf = line[:0]
+	s.buffers.Put(buf)
+	return err
+}
+
+func (s *transportStatSender) sendInt(name string, value int64, sign bool, typ string, rate float32, tags []statsd.Tag) error {
+	buf := s.buffers.Get().(*[]byte)
+	return s.send(s.encoder.appendInt(*buf, name, value, sign, typ, rate, tags), buf)
+}
+
+func (s *transportStatSender) sendFloat(name string, value float64, sign bool, typ string, rate float32, tags []statsd.Tag) error {
+	buf := s.buffers.Get().(*[]byte)
+	if sign {
+		return s.send(s.encoder.AppendDelta(*buf, name, value, typ, rate, tags...), buf)
+	}
+	return s.send(s.encoder.Append(*buf, name, value, typ, rate, tags...), buf)
+}
+
+func (s *transportStatSender) Inc(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, value, false, "c", rate, tags)
+}
+
+func (s *transportStatSender) Dec(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, -value, false, "c", rate, tags)
+}
+
+func (s *transportStatSender) Gauge(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, value, false, "g", rate, tags)
+}
+
+func (s *transportStatSender) GaugeDelta(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, value, true, "g", rate, tags)
+}
+
+func (s *transportStatSender) Timing(stat string, delta int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, delta, false, "ms", rate, tags)
+}
+
+func (s *transportStatSender) TimingDuration(stat string, delta time.Duration, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, float64(delta)/float64(time.Millisecond), false, "ms", rate, tags)
+}
+
+func (s *transportStatSender) Set(stat string, value string, rate float32, tags ...statsd.Tag) error {
+	buf := s.buffers.Get().(*[]byte)
+	return s.send(s.encoder.AppendRaw(*buf, stat, value+"|s", rate, tags...), buf)
+}
+
+func (s *transportStatSender) SetInt(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	return s.sendInt(stat, value, false, "s", rate, tags)
+}
+
+func (s *transportStatSender) Raw(stat string, value string, rate float32, tags ...statsd.Tag) error {
+	buf := s.buffers.Get().(*[]byte)
+	return s.send(s.encoder.AppendRaw(*buf, stat, value, rate, tags...), buf)
+}
+
+func (s *transportStatSender) IncFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, false, "c", rate, tags)
+}
+
+func (s *transportStatSender) GaugeFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, false, "g", rate, tags)
+}
+
+func (s *transportStatSender) GaugeFloatDelta(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, true, "g", rate, tags)
+}
+
+func (s *transportStatSender) TimingFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, false, "ms", rate, tags)
+}
+
+func (s *transportStatSender) HistogramFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, false, "h", rate, tags)
+}
+
+func (s *transportStatSender) DistributionFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.sendFloat(stat, value, false, "d", rate, tags)
+}
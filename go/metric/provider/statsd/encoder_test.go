@@ -0,0 +1,161 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestEncoder(t *testing.T) {
+	tags := []statsd.Tag{{"k", "v"}, {"k2", "v2"}}
+
+	testCases := []struct {
+		name   string
+		format TagFormat
+		want   string
+	}{
+		{
+			name: "Default",
+			want: "a.b:1.5|h|@0.5|#k:v,k2:v2",
+		},
+		{
+			name:   "DogStatsD",
+			format: TagFormatDogStatsD,
+			want:   "a.b:1.5|h|@0.5|#k:v,k2:v2",
+		},
+		{
+			name:   "Influx",
+			format: TagFormatInflux,
+			want:   "a.b,k=v,k2=v2:1.5|h|@0.5",
+		},
+		{
+			name:   "Graphite",
+			format: TagFormatGraphite,
+			want:   "a.b;k=v;k2=v2:1.5|h|@0.5",
+		},
+		{
+			name:   "Etsy",
+			format: TagFormatEtsy,
+			want:   "a.b:1.5|h|@0.5",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncoder(tt.format)
+			assert.Equal(t, tt.want, string(e.Append(nil, "a.b", 1.5, "h", 0.5, tags...)))
+		})
+	}
+}
+
+func TestTransportStatSender(t *testing.T) {
+	tags := []statsd.Tag{{"k", "v"}}
+
+	testCases := []struct {
+		name string
+		send func(StatSender) error
+		want string
+	}{
+		{
+			name: "Inc",
+			send: func(s StatSender) error { return s.Inc("a", 2, 1.0, tags...) },
+			want: "a:2|c|#k:v",
+		},
+		{
+			name: "Dec",
+			send: func(s StatSender) error { return s.Dec("a", 2, 1.0) },
+			want: "a:-2|c",
+		},
+		{
+			name: "Gauge",
+			send: func(s StatSender) error { return s.Gauge("a", 3, 1.0) },
+			want: "a:3|g",
+		},
+		{
+			name: "GaugeDelta",
+			send: func(s StatSender) error { return s.GaugeDelta("a", 3, 1.0) },
+			want: "a:+3|g",
+		},
+		{
+			name: "NegativeGaugeDelta",
+			send: func(s StatSender) error { return s.GaugeDelta("a", -3, 1.0) },
+			want: "a:-3|g",
+		},
+		{
+			name: "TimingDuration",
+			send: func(s StatSender) error { return s.TimingDuration("a", 1500*time.Microsecond, 0.25) },
+			want: "a:1.5|ms|@0.25",
+		},
+		{
+			name: "Set",
+			send: func(s StatSender) error { return s.Set("a", "x", 1.0) },
+			want: "a:x|s",
+		},
+		{
+			name: "Raw",
+			send: func(s StatSender) error { return s.Raw("a", "1|c", 1.0) },
+			want: "a:1|c",
+		},
+		{
+			name: "IncFloat",
+			send: func(s StatSender) error { return s.IncFloat("a", 0.5, 1.0) },
+			want: "a:0.5|c",
+		},
+		{
+			name: "GaugeFloatDelta",
+			send: func(s StatSender) error { return s.GaugeFloatDelta("a", 0.5, 1.0) },
+			want: "a:+0.5|g",
+		},
+		{
+			name: "HistogramFloat",
+			send: func(s StatSender) error { return s.HistogramFloat("a", 0.5, 1.0) },
+			want: "a:0.5|h",
+		},
+		{
+			name: "DistributionFloat",
+			send: func(s StatSender) error { return s.DistributionFloat("a", 0.5, 1.0) },
+			want: "a:0.5|d",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &recordingSender{}
+			require.NoError(t, tt.send(NewTransportStatSender(rs, TagFormatDogStatsD)))
+			assert.Equal(t, []string{tt.want}, rs.sent())
+		})
+	}
+}
+
+func TestProviderTransport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	transport, err := NewUDPTransport(conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer transport.Close()
+
+	mp := NewMeterProvider(
+		WithTransport(transport),
+		WithTagFormat(TagFormatInflux),
+		WithResourceTags(false),
+		WithHistogramType(HistogramTypeDistribution),
+	)
+	hist, err := mp.Meter("").Float64Histogram("latency")
+	require.NoError(t, err)
+	hist.Record(context.Background(), 0.25, metric.WithAttributes(attribute.String("k", "v")))
+
+	buf := make([]byte, MTUUDP)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "latency,k=v:0.25|d", string(buf[:n]))
+}
@@ -0,0 +1,134 @@
+package statsd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Environment variables configuring the MeterProvider, applied before the
+// options.
+const (
+	// envEndpoint is the endpoint, as accepted by WithEndpoint.
+	envEndpoint = "OTEL_STATSD_ENDPOINT"
+	// envProtocol is the scheme of an endpoint without one: udp, tcp, unix
+	// or unixgram.
+	envProtocol = "OTEL_STATSD_PROTOCOL"
+	// envPrefix is the prefix of the metric names.
+	envPrefix = "OTEL_STATSD_PREFIX"
+	// envInterval is the interval between collections, in milliseconds.
+	envInterval = "OTEL_METRIC_EXPORT_INTERVAL"
+	// envWorkers is the number of workers.
+	envWorkers = "OTEL_STATSD_WORKERS"
+	// envTagFormat is the tag format: dogstatsd, influx, graphite or etsy.
+	envTagFormat = "OTEL_STATSD_TAG_FORMAT"
+	// envMaxPacketSize enables batching with the maximum datagram size.
+	envMaxPacketSize = "OTEL_STATSD_MAX_PACKET_SIZE"
+)
+
+// applyEnv returns cfg with the configuration of the environment variables.
+// Invalid values are reported with otel.Handle and ignored.
+func applyEnv(cfg config) config {
+	endpoint, errs := envEndpointOr(cfg.Endpoint)
+	for _, err := range errs {
+		otel.Handle(err)
+	}
+	cfg.Endpoint = endpoint
+
+	if prefix, ok := os.LookupEnv(envPrefix); ok {
+		cfg.Prefix = prefix
+	}
+
+	if v, ok := lookupEnvInt(envInterval); ok {
+		if v <= 0 {
+			otel.Handle(fmt.Errorf("invalid %s %d: want a positive number of milliseconds", envInterval, v))
+		} else {
+			cfg.Interval = time.Duration(v) * time.Millisecond
+		}
+	}
+
+	if v, ok := lookupEnvInt(envWorkers); ok {
+		if v < 0 {
+			otel.Handle(fmt.Errorf("invalid %s %d: want a non-negative number", envWorkers, v))
+		} else {
+			cfg.Workers = v
+		}
+	}
+
+	if v, ok := os.LookupEnv(envTagFormat); ok {
+		format, err := parseTagFormat(v)
+		if err != nil {
+			otel.Handle(fmt.Errorf("invalid %s: %w", envTagFormat, err))
+		} else {
+			cfg.TagFormat = format
+		}
+	}
+
+	if v, ok := lookupEnvInt(envMaxPacketSize); ok {
+		if v <= 0 {
+			otel.Handle(fmt.Errorf("invalid %s %d: want a positive number of bytes", envMaxPacketSize, v))
+		} else {
+			cfg.BatchSize = v
+		}
+	}
+
+	return cfg
+}
+
+// envEndpointOr returns the endpoint of the environment variables, or
+// endpoint if they do not set one, with the errors of the invalid values.
+func envEndpointOr(endpoint string) (string, []error) {
+	var errs []error
+	v, hasEndpoint := os.LookupEnv(envEndpoint)
+	if hasEndpoint {
+		endpoint = v
+	}
+	if protocol, ok := os.LookupEnv(envProtocol); ok {
+		switch {
+		case strings.Contains(endpoint, "://"):
+			if hasEndpoint {
+				errs = append(errs, fmt.Errorf("%s %q ignored: %s %q has a scheme", envProtocol, protocol, envEndpoint, endpoint))
+			}
+		case protocol == "udp" || protocol == "tcp" || protocol == "unix" || protocol == "unixgram":
+			endpoint = protocol + "://" + endpoint
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s %q: want udp, tcp, unix or unixgram", envProtocol, protocol))
+		}
+	}
+	return endpoint, errs
+}
+
+// lookupEnvInt returns the integer value of the environment variable key, if
+// it is set and valid.
+func lookupEnvInt(key string) (int, bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		otel.Handle(fmt.Errorf("invalid %s %q: want an integer", key, v))
+		return 0, false
+	}
+	return i, true
+}
+
+// parseTagFormat returns the TagFormat named s.
+func parseTagFormat(s string) (TagFormat, error) {
+	switch strings.ToLower(s) {
+	case "dogstatsd", "datadog":
+		return TagFormatDogStatsD, nil
+	case "influx", "influxdb":
+		return TagFormatInflux, nil
+	case "graphite":
+		return TagFormatGraphite, nil
+	case "etsy", "none":
+		return TagFormatEtsy, nil
+	default:
+		return 0, fmt.Errorf("unknown tag format %q: want dogstatsd, influx, graphite or etsy", s)
+	}
+}
@@ -0,0 +1,141 @@
+package statsd
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+// handledErrors collects the errors passed to otel.Handle.
+func handledErrors(t *testing.T) *[]error {
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { log.Print(err) }))
+	})
+	return &errs
+}
+
+func TestApplyEnv(t *testing.T) {
+	defaults := config{Endpoint: defaultAddress, Interval: defaultInterval}
+
+	testCases := []struct {
+		name string
+		env  map[string]string
+		want config
+		errs []string
+	}{
+		{
+			name: "Unset",
+			want: defaults,
+		},
+		{
+			name: "All",
+			env: map[string]string{
+				envEndpoint:      "statsd:9125",
+				envProtocol:      "tcp",
+				envPrefix:        "app",
+				envInterval:      "5000",
+				envWorkers:       "4",
+				envTagFormat:     "influx",
+				envMaxPacketSize: "8192",
+			},
+			want: config{
+				Endpoint:  "tcp://statsd:9125",
+				Prefix:    "app",
+				Interval:  5 * time.Second,
+				Workers:   4,
+				TagFormat: TagFormatInflux,
+				BatchSize: 8192,
+			},
+		},
+		{
+			name: "ProtocolOnly",
+			env:  map[string]string{envProtocol: "tcp"},
+			want: config{Endpoint: "tcp://" + defaultAddress, Interval: defaultInterval},
+		},
+		{
+			name: "EndpointWithScheme",
+			env:  map[string]string{envEndpoint: "unixgram:///var/run/statsd.sock", envProtocol: "udp"},
+			want: config{Endpoint: "unixgram:///var/run/statsd.sock", Interval: defaultInterval},
+			errs: []string{`OTEL_STATSD_PROTOCOL "udp" ignored: OTEL_STATSD_ENDPOINT "unixgram:///var/run/statsd.sock" has a scheme`},
+		},
+		{
+			name: "Invalid",
+			env: map[string]string{
+				envProtocol:      "http",
+				envInterval:      "0",
+				envWorkers:       "many",
+				envTagFormat:     "json",
+				envMaxPacketSize: "1k",
+			},
+			want: defaults,
+			errs: []string{
+				`invalid OTEL_STATSD_PROTOCOL "http": want udp, tcp, unix or unixgram`,
+				`invalid OTEL_METRIC_EXPORT_INTERVAL 0: want a positive number of milliseconds`,
+				`invalid OTEL_STATSD_WORKERS "many": want an integer`,
+				`invalid OTEL_STATSD_TAG_FORMAT: unknown tag format "json": want dogstatsd, influx, graphite or etsy`,
+				`invalid OTEL_STATSD_MAX_PACKET_SIZE "1k": want an integer`,
+			},
+		},
+		{
+			name: "Negative",
+			env: map[string]string{
+				envInterval:      "-1",
+				envWorkers:       "-4",
+				envMaxPacketSize: "-8192",
+			},
+			want: defaults,
+			errs: []string{
+				`invalid OTEL_METRIC_EXPORT_INTERVAL -1: want a positive number of milliseconds`,
+				`invalid OTEL_STATSD_WORKERS -4: want a non-negative number`,
+				`invalid OTEL_STATSD_MAX_PACKET_SIZE -8192: want a positive number of bytes`,
+			},
+		},
+		{
+			name: "Zero",
+			env: map[string]string{
+				envWorkers:       "0",
+				envMaxPacketSize: "0",
+			},
+			want: defaults,
+			errs: []string{
+				`invalid OTEL_STATSD_MAX_PACKET_SIZE 0: want a positive number of bytes`,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := handledErrors(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			assert.Equal(t, tt.want, applyEnv(defaults))
+
+			var got []string
+			for _, err := range *errs {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.errs, got)
+		})
+	}
+}
+
+func TestProviderEnvBeforeOptions(t *testing.T) {
+	t.Setenv(envPrefix, "env")
+	t.Setenv(envInterval, "1000")
+
+	mp := NewMeterProvider(WithPrefix("option"))
+	assert.Equal(t, "option", mp.prefix)
+	assert.Equal(t, time.Second, mp.interval)
+	require.NoError(t, mp.Shutdown(context.Background()))
+}
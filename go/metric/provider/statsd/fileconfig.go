@@ -0,0 +1,488 @@
+package statsd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the configuration document of NewMeterProviderFromConfig, in
+// YAML or JSON. Durations are strings like "10s" or "100ms".
+//
+//	endpoint: unixgram:///var/run/datadog/dsd.socket
+//	tag_format: dogstatsd
+//	prefix: checkout
+//	interval: 10s
+//	sample_rate: 0.5
+//	max_packet_size: 8192
+//	workers:
+//	  count: 2
+//	  backpressure: drop_oldest
+//	resource_tags:
+//	  allow: [service.name, deployment.environment]
+//	views:
+//	  - match: {name: "http.server.*"}
+//	    allow_attributes: [http.route]
+type FileConfig struct {
+	// Endpoint is the endpoint, as accepted by WithEndpoint.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// TLS enables TLS with a tcp:// endpoint.
+	TLS *FileTLSConfig `yaml:"tls" json:"tls"`
+	// TagFormat is dogstatsd, influx, graphite or etsy.
+	TagFormat string `yaml:"tag_format" json:"tag_format"`
+	// Prefix is the prefix of the metric names.
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Interval is the interval between collections.
+	Interval string `yaml:"interval" json:"interval"`
+	// MaxPacketSize enables batching with the maximum datagram size.
+	MaxPacketSize int `yaml:"max_packet_size" json:"max_packet_size"`
+	// BatchInterval is the maximum time a stat waits in a batch.
+	BatchInterval string `yaml:"batch_interval" json:"batch_interval"`
+	// HistogramType is timing, histogram, distribution or auto.
+	HistogramType string `yaml:"histogram_type" json:"histogram_type"`
+	// Aggregation enables the aggregation of the measurements.
+	Aggregation bool `yaml:"aggregation" json:"aggregation"`
+	// SampleRate is the sample rate of the synchronous instruments.
+	SampleRate *float32 `yaml:"sample_rate" json:"sample_rate"`
+	// InstrumentSampleRates are the sample rates by instrument name.
+	InstrumentSampleRates map[string]float32 `yaml:"instrument_sample_rates" json:"instrument_sample_rates"`
+	// CardinalityLimit is the maximum number of attribute sets by
+	// instrument.
+	CardinalityLimit int `yaml:"cardinality_limit" json:"cardinality_limit"`
+	// Workers configures the workers.
+	Workers *FileWorkersConfig `yaml:"workers" json:"workers"`
+	// ResourceTags configures the tags of the resource attributes.
+	ResourceTags *FileResourceTagsConfig `yaml:"resource_tags" json:"resource_tags"`
+	// Views are the views, the first matching an instrument applies.
+	Views []FileViewConfig `yaml:"views" json:"views"`
+}
+
+// FileTLSConfig is the TLS configuration of a FileConfig.
+type FileTLSConfig struct {
+	// CAFile is the PEM file of the certificate authorities. Default is the
+	// system pool.
+	CAFile string `yaml:"ca_file" json:"ca_file"`
+	// CertFile and KeyFile are the PEM files of the client certificate.
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	// ServerName overrides the name of the server certificate.
+	ServerName string `yaml:"server_name" json:"server_name"`
+	// InsecureSkipVerify disables the verification of the server
+	// certificate.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
+
+// FileWorkersConfig is the worker configuration of a FileConfig.
+type FileWorkersConfig struct {
+	// Count is the number of workers, if set.
+	Count *int `yaml:"count" json:"count"`
+	// BufferSize is the size of the worker chan buffer, if set.
+	BufferSize *int `yaml:"buffer_size" json:"buffer_size"`
+	// Backpressure is block, drop_newest, drop_oldest or block_timeout.
+	Backpressure string `yaml:"backpressure" json:"backpressure"`
+	// BackpressureTimeout is the timeout of block_timeout.
+	BackpressureTimeout string `yaml:"backpressure_timeout" json:"backpressure_timeout"`
+}
+
+// FileResourceTagsConfig is the resource tag configuration of a FileConfig.
+type FileResourceTagsConfig struct {
+	// Disabled disables the resource tags.
+	Disabled bool `yaml:"disabled" json:"disabled"`
+	// Allow are the only resource attributes sent, if set.
+	Allow []string `yaml:"allow" json:"allow"`
+	// Deny are resource attributes not sent.
+	Deny []string `yaml:"deny" json:"deny"`
+	// Rename are the tag names of resource attributes.
+	Rename map[string]string `yaml:"rename" json:"rename"`
+}
+
+// FileViewConfig is a view of a FileConfig.
+type FileViewConfig struct {
+	// Match are the criteria of the instruments of the view.
+	Match FileViewMatch `yaml:"match" json:"match"`
+	// Name renames the instrument.
+	Name string `yaml:"name" json:"name"`
+	// Drop drops the instrument.
+	Drop bool `yaml:"drop" json:"drop"`
+	// AllowAttributes are the only attributes sent, if set.
+	AllowAttributes []string `yaml:"allow_attributes" json:"allow_attributes"`
+	// DenyAttributes are attributes not sent.
+	DenyAttributes []string `yaml:"deny_attributes" json:"deny_attributes"`
+	// HistogramType is timing, histogram, distribution or auto.
+	HistogramType string `yaml:"histogram_type" json:"histogram_type"`
+	// SampleRate is the sample rate of the instrument.
+	SampleRate float32 `yaml:"sample_rate" json:"sample_rate"`
+	// CardinalityLimit is the maximum number of attribute sets of the
+	// instrument, -1 for no limit.
+	CardinalityLimit int `yaml:"cardinality_limit" json:"cardinality_limit"`
+}
+
+// FileViewMatch are the criteria of a FileViewConfig.
+type FileViewMatch struct {
+	// Name is the instrument name, with * and ? wildcards.
+	Name string `yaml:"name" json:"name"`
+	// Kind is counter, up_down_counter, histogram, gauge,
+	// observable_counter, observable_up_down_counter or observable_gauge.
+	Kind string `yaml:"kind" json:"kind"`
+	// Unit is the instrument unit.
+	Unit string `yaml:"unit" json:"unit"`
+	// Scope is the instrumentation scope name.
+	Scope string `yaml:"scope" json:"scope"`
+}
+
+// NewMeterProviderFromConfig returns a MeterProvider configured by the YAML
+// or JSON document data, after the environment variables and before opts.
+// It returns an error listing every invalid field of the document.
+func NewMeterProviderFromConfig(data []byte, opts ...Option) (*MeterProvider, error) {
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	fileOpts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return NewMeterProvider(append(fileOpts, opts...)...), nil
+}
+
+// ParseConfig parses the YAML or JSON document data. Unknown fields are
+// errors.
+func ParseConfig(data []byte) (*FileConfig, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var cfg FileConfig
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid statsd configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Options validates the configuration and returns its options. The error
+// lists every invalid field.
+func (c *FileConfig) Options() ([]Option, error) {
+	v := &configValidator{}
+	var opts []Option
+
+	if c.Endpoint != "" {
+		v.check("endpoint", validateEndpoint(c.Endpoint))
+		opts = append(opts, WithEndpoint(c.Endpoint))
+	}
+	if c.TLS != nil {
+		// The endpoint may be set by the environment variables, which
+		// are applied before the document.
+		endpoint := c.Endpoint
+		if endpoint == "" {
+			endpoint, _ = envEndpointOr(defaultAddress)
+		}
+		if !strings.HasPrefix(endpoint, "tcp://") {
+			v.errorf("tls", "requires a tcp:// endpoint")
+		}
+		tlsConfig, err := c.TLS.config()
+		v.check("tls", err)
+		opts = append(opts, WithTLSConfig(tlsConfig))
+	}
+	if c.TagFormat != "" {
+		format, err := parseTagFormat(c.TagFormat)
+		v.check("tag_format", err)
+		opts = append(opts, WithTagFormat(format))
+	}
+	if c.Prefix != "" {
+		opts = append(opts, WithPrefix(c.Prefix))
+	}
+	if c.Interval != "" {
+		opts = append(opts, WithInterval(v.duration("interval", c.Interval)))
+	}
+	if c.MaxPacketSize != 0 {
+		if c.MaxPacketSize < 0 {
+			v.errorf("max_packet_size", "must be positive, got %d", c.MaxPacketSize)
+		}
+		opts = append(opts, WithBatching(c.MaxPacketSize))
+	}
+	if c.BatchInterval != "" {
+		opts = append(opts, WithBatchInterval(v.duration("batch_interval", c.BatchInterval)))
+	}
+	if c.HistogramType != "" {
+		t, err := parseHistogramType(c.HistogramType)
+		v.check("histogram_type", err)
+		opts = append(opts, WithHistogramType(t))
+	}
+	if c.Aggregation {
+		opts = append(opts, WithAggregation(true))
+	}
+	if c.SampleRate != nil {
+		v.sampleRate("sample_rate", *c.SampleRate)
+		opts = append(opts, WithSampleRate(*c.SampleRate))
+	}
+	for name, rate := range c.InstrumentSampleRates {
+		v.sampleRate("instrument_sample_rates."+name, rate)
+		opts = append(opts, WithInstrumentSampleRate(name, rate))
+	}
+	if c.CardinalityLimit != 0 {
+		opts = append(opts, WithCardinalityLimit(c.CardinalityLimit))
+	}
+	if c.Workers != nil {
+		opts = append(opts, c.Workers.options(v)...)
+	}
+	if c.ResourceTags != nil {
+		opts = append(opts, c.ResourceTags.options()...)
+	}
+	for i, view := range c.Views {
+		opts = append(opts, WithView(view.view(v, fmt.Sprintf("views[%d]", i))))
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func (c *FileTLSConfig) config() (*tls.Config, error) {
+	ret := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		ret.RootCAs = x509.NewCertPool()
+		if !ret.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in ca_file %q", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return ret, nil
+}
+
+func (c *FileWorkersConfig) options(v *configValidator) []Option {
+	var opts []Option
+	if c.Count != nil {
+		if *c.Count < 0 {
+			v.errorf("workers.count", "must not be negative, got %d", *c.Count)
+		}
+		opts = append(opts, WithWorkers(*c.Count))
+	}
+	if c.BufferSize != nil {
+		if *c.BufferSize < 0 {
+			v.errorf("workers.buffer_size", "must not be negative, got %d", *c.BufferSize)
+		}
+		opts = append(opts, WithWorkerChanBufferSize(*c.BufferSize))
+	}
+	if c.Backpressure != "" {
+		policy, err := parseBackpressurePolicy(c.Backpressure)
+		v.check("workers.backpressure", err)
+		opts = append(opts, WithBackpressure(policy))
+	}
+	if c.BackpressureTimeout != "" {
+		opts = append(opts, WithBackpressureTimeout(v.duration("workers.backpressure_timeout", c.BackpressureTimeout)))
+	}
+	return opts
+}
+
+func (c *FileResourceTagsConfig) options() []Option {
+	if c.Disabled {
+		return []Option{WithResourceTags(false)}
+	}
+
+	var opts []Option
+	if len(c.Allow) > 0 {
+		opts = append(opts, WithResourceTagAllowlist(attributeKeys(c.Allow)...))
+	}
+	if len(c.Deny) > 0 {
+		opts = append(opts, WithResourceTagDenylist(attributeKeys(c.Deny)...))
+	}
+	for from, to := range c.Rename {
+		opts = append(opts, WithResourceTagRename(attribute.Key(from), to))
+	}
+	return opts
+}
+
+func (c *FileViewConfig) view(v *configValidator, path string) View {
+	errs := len(v.errs)
+	m := c.Match
+	if m.Name == "" && m.Kind == "" && m.Unit == "" && m.Scope == "" {
+		v.errorf(path+".match", "no criteria")
+	}
+	if strings.ContainsAny(m.Name, "*?") && c.Name != "" {
+		v.errorf(path+".name", "cannot rename the instruments matching %q", m.Name)
+	}
+
+	criteria := sdkmetric.Instrument{
+		Name:  m.Name,
+		Unit:  m.Unit,
+		Scope: instrumentation.Scope{Name: m.Scope},
+	}
+	if m.Kind != "" {
+		kind, err := parseInstrumentKind(m.Kind)
+		v.check(path+".match.kind", err)
+		criteria.Kind = kind
+	}
+
+	mask := Stream{
+		Name:             c.Name,
+		Drop:             c.Drop,
+		SampleRate:       c.SampleRate,
+		CardinalityLimit: c.CardinalityLimit,
+	}
+	if c.SampleRate != 0 {
+		v.sampleRate(path+".sample_rate", c.SampleRate)
+	}
+	if c.HistogramType != "" {
+		t, err := parseHistogramType(c.HistogramType)
+		v.check(path+".histogram_type", err)
+		mask.HistogramType = t
+	}
+	switch {
+	case len(c.AllowAttributes) > 0 && len(c.DenyAttributes) > 0:
+		v.errorf(path, "allow_attributes and deny_attributes are exclusive")
+	case len(c.AllowAttributes) > 0:
+		mask.AttributeFilter = attribute.NewAllowKeysFilter(attributeKeys(c.AllowAttributes)...)
+	case len(c.DenyAttributes) > 0:
+		mask.AttributeFilter = attribute.NewDenyKeysFilter(attributeKeys(c.DenyAttributes)...)
+	}
+
+	if len(v.errs) > errs {
+		// NewView would report the invalid view with otel.Handle.
+		return emptyView
+	}
+	return NewView(criteria, mask)
+}
+
+// configValidator collects the errors of the fields of a FileConfig.
+type configValidator struct {
+	errs []error
+}
+
+func (v *configValidator) errorf(field, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (v *configValidator) check(field string, err error) {
+	if err != nil {
+		v.errorf(field, "%v", err)
+	}
+}
+
+func (v *configValidator) duration(field, s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		v.errorf(field, "invalid duration %q", s)
+	} else if d <= 0 {
+		v.errorf(field, "must be positive, got %q", s)
+	}
+	return d
+}
+
+func (v *configValidator) sampleRate(field string, rate float32) {
+	if rate <= 0 || rate > 1 {
+		v.errorf(field, "must be in (0, 1], got %v", rate)
+	}
+}
+
+func (v *configValidator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid statsd configuration:\n%w", errors.Join(v.errs...))
+}
+
+// validateEndpoint returns an error if NewTransport would not accept
+// endpoint.
+func validateEndpoint(endpoint string) error {
+	scheme, addr, ok := strings.Cut(endpoint, "://")
+	if !ok {
+		scheme, addr = "udp", endpoint
+	}
+	switch scheme {
+	case "udp", "tcp":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return err
+		}
+	case "unix", "unixgram":
+		if addr == "" {
+			return fmt.Errorf("missing socket path in %q", endpoint)
+		}
+	default:
+		return fmt.Errorf("unsupported scheme %q in %q", scheme, endpoint)
+	}
+	return nil
+}
+
+func attributeKeys(keys []string) []attribute.Key {
+	ret := make([]attribute.Key, len(keys))
+	for i, k := range keys {
+		ret[i] = attribute.Key(k)
+	}
+	return ret
+}
+
+func parseHistogramType(s string) (HistogramType, error) {
+	switch s {
+	case "timing":
+		return HistogramTypeTiming, nil
+	case "histogram":
+		return HistogramTypeHistogram, nil
+	case "distribution":
+		return HistogramTypeDistribution, nil
+	case "auto":
+		return HistogramTypeAuto, nil
+	default:
+		return 0, fmt.Errorf("unknown histogram type %q: want timing, histogram, distribution or auto", s)
+	}
+}
+
+func parseBackpressurePolicy(s string) (BackpressurePolicy, error) {
+	switch s {
+	case "block":
+		return BackpressureBlock, nil
+	case "drop_newest":
+		return BackpressureDropNewest, nil
+	case "drop_oldest":
+		return BackpressureDropOldest, nil
+	case "block_timeout":
+		return BackpressureBlockTimeout, nil
+	default:
+		return 0, fmt.Errorf("unknown backpressure policy %q: want block, drop_newest, drop_oldest or block_timeout", s)
+	}
+}
+
+func parseInstrumentKind(s string) (sdkmetric.InstrumentKind, error) {
+	switch s {
+	case "counter":
+		return sdkmetric.InstrumentKindCounter, nil
+	case "up_down_counter":
+		return sdkmetric.InstrumentKindUpDownCounter, nil
+	case "histogram":
+		return sdkmetric.InstrumentKindHistogram, nil
+	case "gauge":
+		return sdkmetric.InstrumentKindGauge, nil
+	case "observable_counter":
+		return sdkmetric.InstrumentKindObservableCounter, nil
+	case "observable_up_down_counter":
+		return sdkmetric.InstrumentKindObservableUpDownCounter, nil
+	case "observable_gauge":
+		return sdkmetric.InstrumentKindObservableGauge, nil
+	default:
+		return 0, fmt.Errorf("unknown instrument kind %q", s)
+	}
+}
@@ -0,0 +1,157 @@
+package statsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+)
+
+func TestNewMeterProviderFromConfig(t *testing.T) {
+	testCases := []struct {
+		name string
+		doc  string
+	}{
+		{
+			name: "YAML",
+			doc: `
+prefix: checkout
+interval: 10s
+sample_rate: 1
+histogram_type: distribution
+workers:
+  count: 2
+  backpressure: drop_oldest
+resource_tags:
+  disabled: true
+views:
+  - match: {name: "http.*", kind: histogram}
+    allow_attributes: [http.route]
+  - match: {name: debug}
+    drop: true
+`,
+		},
+		{
+			name: "JSON",
+			doc: `{
+  "prefix": "checkout",
+  "interval": "10s",
+  "sample_rate": 1,
+  "histogram_type": "distribution",
+  "workers": {"count": 2, "backpressure": "drop_oldest"},
+  "resource_tags": {"disabled": true},
+  "views": [
+    {"match": {"name": "http.*", "kind": "histogram"}, "allow_attributes": ["http.route"]},
+    {"match": {"name": "debug"}, "drop": true}
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(mocks.MockStatSenderMethod{
+				Method: "Raw", S: "checkout.http.latency", S2: "1.5|d", F: 1.0,
+				Tags: []statsd.Tag{{"http.route", "/cart"}},
+			})
+
+			mp, err := NewMeterProviderFromConfig([]byte(tt.doc), WithStatsdClient(rs))
+			require.NoError(t, err)
+			assert.Equal(t, 10*time.Second, mp.interval)
+
+			m := mp.Meter("")
+			hist, err := m.Float64Histogram("http.latency")
+			require.NoError(t, err)
+			hist.Record(ctx, 1.5, metric.WithAttributes(
+				attribute.String("http.route", "/cart"),
+				attribute.String("user.id", "42"),
+			))
+			debug, err := m.Int64Counter("debug")
+			require.NoError(t, err)
+			debug.Add(ctx, 1)
+
+			require.NoError(t, mp.Shutdown(ctx))
+			rs.CHECK(t)
+			require.Len(t, rs.Output, 1)
+			assert.Len(t, rs.Output[0].Tags, 1)
+		})
+	}
+}
+
+func TestNewMeterProviderFromConfigErrors(t *testing.T) {
+	doc := `
+endpoint: http://statsd:8125
+tls: {ca_file: /missing/ca.pem}
+tag_format: json
+interval: soon
+max_packet_size: -1
+sample_rate: 2
+instrument_sample_rates: {hits: 0}
+workers:
+  count: -1
+  backpressure: wait
+views:
+  - drop: true
+  - match: {name: "http.*", kind: timer}
+    name: http
+    allow_attributes: [a]
+    deny_attributes: [b]
+`
+	_, err := NewMeterProviderFromConfig([]byte(doc))
+	require.EqualError(t, err, `invalid statsd configuration:
+endpoint: unsupported scheme "http" in "http://statsd:8125"
+tls: requires a tcp:// endpoint
+tls: open /missing/ca.pem: no such file or directory
+tag_format: unknown tag format "json": want dogstatsd, influx, graphite or etsy
+interval: invalid duration "soon"
+max_packet_size: must be positive, got -1
+sample_rate: must be in (0, 1], got 2
+instrument_sample_rates.hits: must be in (0, 1], got 0
+workers.count: must not be negative, got -1
+workers.backpressure: unknown backpressure policy "wait": want block, drop_newest, drop_oldest or block_timeout
+views[0].match: no criteria
+views[1].name: cannot rename the instruments matching "http.*"
+views[1].match.kind: unknown instrument kind "timer"
+views[1]: allow_attributes and deny_attributes are exclusive`)
+
+	_, err = NewMeterProviderFromConfig([]byte("prefx: app\n"))
+	require.ErrorContains(t, err, "field prefx not found")
+}
+
+func TestNewMeterProviderFromConfigEnv(t *testing.T) {
+	t.Setenv(envWorkers, "4")
+	t.Setenv(envEndpoint, "tcp://127.0.0.1:8125")
+
+	// The workers of the environment are kept when the document does not
+	// set their count, and the endpoint of the environment allows tls.
+	mp, err := NewMeterProviderFromConfig([]byte(`
+tls: {insecure_skip_verify: true}
+workers: {backpressure: drop_oldest}
+`))
+	require.NoError(t, err)
+	defer mp.Shutdown(context.Background())
+	w := mp.destinations[0].workers
+	require.NotNil(t, w)
+	assert.Equal(t, 4, w.workers)
+	assert.Equal(t, BackpressureDropOldest, w.backpressure)
+
+	// An explicit count overrides the environment.
+	mp, err = NewMeterProviderFromConfig([]byte("workers: {count: 0}\n"))
+	require.NoError(t, err)
+	defer mp.Shutdown(context.Background())
+	assert.Nil(t, mp.destinations[0].workers)
+
+	t.Setenv(envEndpoint, "udp://127.0.0.1:8125")
+	_, err = NewMeterProviderFromConfig([]byte("tls: {}\n"))
+	require.EqualError(t, err, "invalid statsd configuration:\ntls: requires a tcp:// endpoint")
+}
@@ -4,61 +4,263 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/embedded"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
-type int64Inst struct {
-	embedded.Int64Counter
-	embedded.Int64UpDownCounter
-	embedded.Int64Gauge
-	embedded.Int64Histogram
+// stream is what an instrument is sent as: the StatsD metric name and the
+// tags added to all of its measurements.
+type stream struct {
+	name string
+	tags []statsd.Tag
+
+	tagCache tagCache
+}
+
+// instrumentID is a comparable unique identifier of a synchronous instrument.
+type instrumentID struct {
+	scope instrumentation.Scope
+	name  string
+	kind  sdkmetric.InstrumentKind
+	unit  string
+}
 
+func newInstrumentID(i sdkmetric.Instrument) instrumentID {
+	return instrumentID{scope: i.Scope, name: i.Name, kind: i.Kind, unit: i.Unit}
+}
+
+// numberInstrumentID is a comparable unique identifier of a synchronous
+// instrument with values of type N.
+type numberInstrumentID[N int64 | float64] struct {
+	instrumentID
+}
+
+// instrumentImpl is the resolved configuration of a synchronous instrument,
+// shared by all the lookups of the instrument.
+type instrumentImpl struct {
 	provider   *MeterProvider
 	instrument sdkmetric.Instrument
+	id         instrumentID
+	view       Stream
+	stream     *stream
+
+	sampleRate float32
+	// limiter is nil if the instrument has no cardinality limit.
+	limiter *cardinalityLimiter
+}
+
+func newInstrumentImpl(provider *MeterProvider, instrument sdkmetric.Instrument) *instrumentImpl {
+	view := provider.viewOf(instrument)
+	ret := &instrumentImpl{
+		provider:   provider,
+		instrument: instrument,
+		id:         newInstrumentID(instrument),
+		view:       view,
+		stream:     provider.newStream(instrument, view),
+		sampleRate: provider.sampleRateOf(instrument, view),
+	}
+	if limit := provider.cardinalityLimitOf(view); limit > 0 {
+		ret.limiter = newCardinalityLimiter(provider, view.Name, limit)
+	}
+	return ret
+}
+
+// attributes returns the attributes of a measurement with attrs, filtered by
+// the view and limited by the cardinality limit.
+func (i *instrumentImpl) attributes(attrs attribute.Set) attribute.Set {
+	attrs = i.view.filter(attrs)
+	if i.limiter != nil {
+		attrs = i.limiter.attributes(attrs)
+	}
+	return attrs
+}
+
+// record sends val as a StatsD metric of type stat, sampled at the rate set
+// in ctx or else at the rate of the instrument unless stat is a gauge. attrs
+// must have been returned by attributes.
+func (i *instrumentImpl) record(ctx context.Context, stat statType, val float64, attrs attribute.Set) {
+	if i.view.Drop {
+		return
+	}
+	rate := float32(1)
+	if stat.sampleable() {
+		rate = i.sampleRate
+		if r, ok := sampleRateFromContext(ctx); ok {
+			rate = r
+		}
+		if !sampled(rate) {
+			return
+		}
+	}
+	i.provider.record(stat, i.stream, val, rate, attrs)
+}
+
+type int64Inst struct {
+	embedded.Int64Counter
+
+	*instrumentImpl
 }
 
 var _ metric.Int64Counter = (*int64Inst)(nil)
-var _ metric.Int64UpDownCounter = (*int64Inst)(nil)
-var _ metric.Int64Gauge = (*int64Inst)(nil)
-var _ metric.Int64Histogram = (*int64Inst)(nil)
 
 func (i *int64Inst) Add(ctx context.Context, val int64, opts ...metric.AddOption) {
 	c := metric.NewAddConfig(opts)
-	_ = i.provider.statsdClient.Inc(i.instrument.Name, val, 1.0, collectTags(i.provider, c.Attributes())...)
-}
-
-func (i *int64Inst) Record(ctx context.Context, val int64, opts ...metric.RecordOption) {
-	c := metric.NewRecordConfig(opts)
-	_ = i.provider.statsdClient.Timing(i.instrument.Name, int64(val), 1.0, collectTags(i.provider, c.Attributes())...)
+	i.record(ctx, statCount, float64(val), i.attributes(c.Attributes()))
 }
 
 type float64Inst struct {
 	embedded.Float64Counter
-	embedded.Float64UpDownCounter
-	embedded.Float64Gauge
-	embedded.Float64Histogram
 
-	provider   *MeterProvider
-	instrument sdkmetric.Instrument
+	*instrumentImpl
 }
 
 var _ metric.Float64Counter = (*float64Inst)(nil)
-var _ metric.Float64UpDownCounter = (*float64Inst)(nil)
-var _ metric.Float64Gauge = (*float64Inst)(nil)
-var _ metric.Float64Histogram = (*float64Inst)(nil)
 
 func (i *float64Inst) Add(ctx context.Context, val float64, opts ...metric.AddOption) {
 	c := metric.NewAddConfig(opts)
-	_ = i.provider.statsdClient.Inc(i.instrument.Name, int64(val), 1.0, collectTags(i.provider, c.Attributes())...)
+	i.record(ctx, statCount, val, i.attributes(c.Attributes()))
+}
+
+type int64Gauge struct {
+	embedded.Int64Gauge
+
+	*instrumentImpl
 }
 
-func (i *float64Inst) Record(ctx context.Context, val float64, opts ...metric.RecordOption) {
+var _ metric.Int64Gauge = (*int64Gauge)(nil)
+
+func (i *int64Gauge) Record(ctx context.Context, val int64, opts ...metric.RecordOption) {
 	c := metric.NewRecordConfig(opts)
-	_ = i.provider.statsdClient.Timing(i.instrument.Name, int64(val), 1.0, collectTags(i.provider, c.Attributes())...)
+	i.record(ctx, statGauge, float64(val), i.attributes(c.Attributes()))
+}
+
+type float64Gauge struct {
+	embedded.Float64Gauge
+
+	*instrumentImpl
+}
+
+var _ metric.Float64Gauge = (*float64Gauge)(nil)
+
+func (i *float64Gauge) Record(ctx context.Context, val float64, opts ...metric.RecordOption) {
+	c := metric.NewRecordConfig(opts)
+	i.record(ctx, statGauge, val, i.attributes(c.Attributes()))
+}
+
+// upDownCounter sends up-down counters as gauges, either as deltas or as a
+// locally maintained absolute value depending on the UpDownCounterMode.
+type upDownCounter[N int64 | float64] struct {
+	*instrumentImpl
+
+	// totals holds the running value of each attribute set, only used by
+	// UpDownCounterModeAbsolute. It is shared by all the lookups of the
+	// instrument.
+	totals *valueMap[N]
+}
+
+func newUpDownCounter[N int64 | float64](impl *instrumentImpl) *upDownCounter[N] {
+	ret := &upDownCounter[N]{instrumentImpl: impl}
+	if impl.provider.upDownCounterMode == UpDownCounterModeAbsolute && !impl.view.Drop {
+		totals, _ := impl.provider.upDownTotals.LoadOrStore(numberInstrumentID[N]{impl.id}, newValueMap[N]())
+		ret.totals = totals.(*valueMap[N])
+	}
+	return ret
+}
+
+func (i *upDownCounter[N]) add(ctx context.Context, val N, attrs attribute.Set) {
+	attrs = i.attributes(attrs)
+	if i.totals != nil {
+		// The total must be kept even if this measurement is not sampled.
+		i.record(ctx, statGauge, float64(i.totals.add(attrs, val)), attrs)
+		return
+	}
+	i.record(ctx, statGaugeDelta, float64(val), attrs)
+}
+
+type int64UpDownCounter struct {
+	embedded.Int64UpDownCounter
+
+	*upDownCounter[int64]
+}
+
+var _ metric.Int64UpDownCounter = int64UpDownCounter{}
+
+func (i int64UpDownCounter) Add(ctx context.Context, val int64, opts ...metric.AddOption) {
+	c := metric.NewAddConfig(opts)
+	i.add(ctx, val, c.Attributes())
+}
+
+type float64UpDownCounter struct {
+	embedded.Float64UpDownCounter
+
+	*upDownCounter[float64]
+}
+
+var _ metric.Float64UpDownCounter = float64UpDownCounter{}
+
+func (i float64UpDownCounter) Add(ctx context.Context, val float64, opts ...metric.AddOption) {
+	c := metric.NewAddConfig(opts)
+	i.add(ctx, val, c.Attributes())
+}
+
+// histogram sends histogram records as the StatsD type resolved for the
+// instrument.
+type histogram[N int64 | float64] struct {
+	*instrumentImpl
+
+	stat statType
+	// scale converts recorded values to milliseconds for timings.
+	scale float64
+}
+
+func newHistogram[N int64 | float64](impl *instrumentImpl) *histogram[N] {
+	ret := &histogram[N]{
+		instrumentImpl: impl,
+		stat:           impl.provider.histogramTypeOf(impl.instrument, impl.view).statType(),
+		scale:          1,
+	}
+	if ret.stat == statTiming {
+		if scale, ok := durationUnitToMillis(impl.instrument.Unit); ok {
+			ret.scale = scale
+		}
+	}
+	return ret
+}
+
+func (i *histogram[N]) recordValue(ctx context.Context, val N, attrs attribute.Set) {
+	i.record(ctx, i.stat, float64(val)*i.scale, i.attributes(attrs))
+}
+
+type int64Histogram struct {
+	embedded.Int64Histogram
+
+	*histogram[int64]
+}
+
+var _ metric.Int64Histogram = int64Histogram{}
+
+func (i int64Histogram) Record(ctx context.Context, val int64, opts ...metric.RecordOption) {
+	c := metric.NewRecordConfig(opts)
+	i.recordValue(ctx, val, c.Attributes())
+}
+
+type float64Histogram struct {
+	embedded.Float64Histogram
+
+	*histogram[float64]
+}
+
+var _ metric.Float64Histogram = float64Histogram{}
+
+func (i float64Histogram) Record(ctx context.Context, val float64, opts ...metric.RecordOption) {
+	c := metric.NewRecordConfig(opts)
+	i.recordValue(ctx, val, c.Attributes())
 }
 
 // observablID is a comparable unique identifier of an observable.
@@ -113,10 +315,29 @@ type observable[N int64 | float64] struct {
 	observablID[N]
 
 	provider *MeterProvider
+	view     Stream
+	stream   *stream
+
+	// last holds the last value observed for each attribute set, only used
+	// by instruments sent as deltas.
+	last *valueMap[N]
+
+	// mu guards observed, the sum of the values observed for each attribute
+	// set, filtered by the view, since the last flushObservations. It is only
+	// used by sums, so that the observations of the sets made equal by the
+	// view are added up.
+	mu       sync.Mutex
+	observed map[attribute.Distinct]*observation[N]
+}
+
+// observation is the sum of the values observed for attrs.
+type observation[N int64 | float64] struct {
+	attrs attribute.Set
+	val   N
 }
 
 func newObservable[N int64 | float64](provider *MeterProvider, scope instrumentation.Scope, kind sdkmetric.InstrumentKind, name, desc string, u string) *observable[N] {
-	return &observable[N]{
+	ret := &observable[N]{
 		observablID: observablID[N]{
 			name:        name,
 			description: desc,
@@ -126,12 +347,111 @@ func newObservable[N int64 | float64](provider *MeterProvider, scope instrumenta
 		},
 		provider: provider,
 	}
+	instrument := sdkmetric.Instrument{
+		Name:  name,
+		Unit:  u,
+		Kind:  kind,
+		Scope: scope,
+	}
+	ret.view = provider.viewOf(instrument)
+	ret.stream = provider.newStream(instrument, ret.view)
+	if ret.sendsDelta() && !ret.view.Drop {
+		ret.last = newValueMap[N]()
+	}
+	return ret
 }
 
-// observe records the val for the set of attrs.
+// sendsDelta returns true if the observed values are sent as the difference
+// from the previous observation.
+func (o *observable[N]) sendsDelta() bool {
+	switch o.kind {
+	case sdkmetric.InstrumentKindObservableCounter:
+		return true
+	case sdkmetric.InstrumentKindObservableUpDownCounter:
+		return o.provider.observableUpDownCounterMode == UpDownCounterModeDelta
+	}
+	return false
+}
+
+// isSum returns true if the observed values of a collection are added up for
+// each attribute set.
+func (o *observable[N]) isSum() bool {
+	return o.kind == sdkmetric.InstrumentKindObservableCounter ||
+		o.kind == sdkmetric.InstrumentKindObservableUpDownCounter
+}
+
+// observe records the val for the set of attrs. The values of sums are added
+// up and sent once the callbacks of the collection returned.
 func (o *observable[N]) observe(val N, opts ...metric.ObserveOption) {
+	if o.view.Drop {
+		return
+	}
 	c := metric.NewObserveConfig(opts)
-	_ = o.provider.statsdClient.Inc(o.name, int64(val), 1.0, collectTags(o.provider, c.Attributes())...)
+	attrs := o.view.filter(c.Attributes())
+	if !o.isSum() {
+		o.send(val, attrs)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.observed == nil {
+		o.observed = make(map[attribute.Distinct]*observation[N])
+		o.provider.pipes.addObserved(o)
+	}
+	key := attrs.Equivalent()
+	if obs, ok := o.observed[key]; ok {
+		obs.val += val
+		return
+	}
+	o.observed[key] = &observation[N]{attrs: attrs, val: val}
+}
+
+// flushObservations sends the sums observed since the last call.
+func (o *observable[N]) flushObservations() {
+	o.mu.Lock()
+	observed := o.observed
+	o.observed = nil
+	o.mu.Unlock()
+
+	for _, obs := range observed {
+		o.send(obs.val, obs.attrs)
+	}
+}
+
+// send sends the val observed for attrs, as the difference from the previous
+// observation if the instrument is sent as deltas.
+func (o *observable[N]) send(val N, attrs attribute.Set) {
+	if o.last != nil {
+		val = o.delta(val, attrs)
+		if val == 0 {
+			return
+		}
+	}
+
+	stat := statGauge
+	switch {
+	case o.kind == sdkmetric.InstrumentKindObservableCounter:
+		stat = statCount
+	case o.last != nil:
+		stat = statGaugeDelta
+	}
+	o.provider.send(stat, o.stream.name, float64(val), 1.0, collectTags(o.provider, o.stream, attrs))
+}
+
+// delta converts the val observed for attrs to the difference from the
+// previous observation. The first observation is sent as a whole. For
+// counters, a value lower than the previous one is also sent as a whole, as it
+// means the counter was reset.
+func (o *observable[N]) delta(val N, attrs attribute.Set) N {
+	prev, ok := o.last.swap(attrs, val)
+	if !ok {
+		return val
+	}
+	if o.kind == sdkmetric.InstrumentKindObservableCounter && val < prev {
+		return val
+	}
+	return val - prev
 }
 
 var errEmptyAgg = errors.New("no aggregators for observable instrument")
@@ -141,6 +461,9 @@ var errEmptyAgg = errors.New("no aggregators for observable instrument")
 // no-op because it does not have any aggregators. Also, an error is returned
 // if scope defines a Meter other than the one o was created by.
 func (o *observable[N]) registerable(scope instrumentation.Scope) error {
+	if o.view.Drop {
+		return errEmptyAgg
+	}
 	if scope != o.scope {
 		return fmt.Errorf(
 			"invalid registration: observable %q from Meter %q, registered with Meter %q",
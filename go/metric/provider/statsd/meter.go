@@ -44,19 +44,19 @@ func (m *meterImpl) Int64Counter(name string, options ...metric.Int64CounterOpti
 func (m *meterImpl) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
 	cfg := metric.NewInt64UpDownCounterConfig(options...)
 	const kind = sdkmetric.InstrumentKindUpDownCounter
-	return m.int64IP.lookup(kind, name, cfg.Description(), cfg.Unit())
+	return m.int64IP.lookupUpDownCounter(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
 	cfg := metric.NewInt64GaugeConfig(options...)
 	const kind = sdkmetric.InstrumentKindGauge
-	return m.int64IP.lookup(kind, name, cfg.Description(), cfg.Unit())
+	return m.int64IP.lookupGauge(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
 	cfg := metric.NewInt64HistogramConfig(options...)
 	const kind = sdkmetric.InstrumentKindHistogram
-	return m.int64IP.lookup(kind, name, cfg.Description(), cfg.Unit())
+	return m.int64IP.lookupHistogram(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Int64ObservableCounter(name string, options ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
@@ -106,18 +106,19 @@ func (m *meterImpl) Float64Counter(name string, options ...metric.Float64Counter
 func (m *meterImpl) Float64UpDownCounter(name string, options ...metric.Float64UpDownCounterOption) (metric.Float64UpDownCounter, error) {
 	cfg := metric.NewFloat64UpDownCounterConfig(options...)
 	const kind = sdkmetric.InstrumentKindUpDownCounter
-	return m.float64IP.lookup(kind, name, cfg.Description(), cfg.Unit())
+	return m.float64IP.lookupUpDownCounter(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Float64Gauge(name string, options ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
-	// TODO implement me
-	panic("implement me")
+	cfg := metric.NewFloat64GaugeConfig(options...)
+	const kind = sdkmetric.InstrumentKindGauge
+	return m.float64IP.lookupGauge(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
 	cfg := metric.NewFloat64HistogramConfig(options...)
 	const kind = sdkmetric.InstrumentKindHistogram
-	return m.float64IP.lookup(kind, name, cfg.Description(), cfg.Unit())
+	return m.float64IP.lookupHistogram(kind, name, cfg.Description(), cfg.Unit())
 }
 
 func (m *meterImpl) Float64ObservableCounter(name string, options ...metric.Float64ObservableCounterOption) (metric.Float64ObservableCounter, error) {
@@ -331,16 +332,40 @@ func newInt64InstProvider(p *MeterProvider, pipes *pipeline, s instrumentation.S
 	return &int64InstProvider{provider: p, pipes: pipes, scope: s}
 }
 
-// lookup returns the resolved instrumentImpl.
-func (p *int64InstProvider) lookup(kind sdkmetric.InstrumentKind, name, desc string, u string) (*int64Inst, error) {
-	i := sdkmetric.Instrument{
+// instrument returns the sdkmetric.Instrument describing an instrument.
+func (p *int64InstProvider) instrument(kind sdkmetric.InstrumentKind, name, desc string, u string) sdkmetric.Instrument {
+	return sdkmetric.Instrument{
 		Name:        name,
 		Description: "", // TODO
 		Unit:        u,
 		Kind:        kind,
 		Scope:       p.scope,
 	}
-	return &int64Inst{provider: p.provider, instrument: i}, nil
+}
+
+// impl returns the resolved instrumentImpl.
+func (p *int64InstProvider) impl(kind sdkmetric.InstrumentKind, name, desc string, u string) *instrumentImpl {
+	return p.provider.instrumentImpl(p.instrument(kind, name, desc, u))
+}
+
+// lookup returns the resolved counter instrument.
+func (p *int64InstProvider) lookup(kind sdkmetric.InstrumentKind, name, desc string, u string) (*int64Inst, error) {
+	return &int64Inst{instrumentImpl: p.impl(kind, name, desc, u)}, nil
+}
+
+// lookupGauge returns the resolved gauge instrument.
+func (p *int64InstProvider) lookupGauge(kind sdkmetric.InstrumentKind, name, desc string, u string) (*int64Gauge, error) {
+	return &int64Gauge{instrumentImpl: p.impl(kind, name, desc, u)}, nil
+}
+
+// lookupHistogram returns the resolved histogram instrument.
+func (p *int64InstProvider) lookupHistogram(kind sdkmetric.InstrumentKind, name, desc string, u string) (int64Histogram, error) {
+	return int64Histogram{histogram: newHistogram[int64](p.impl(kind, name, desc, u))}, nil
+}
+
+// lookupUpDownCounter returns the resolved up-down counter instrument.
+func (p *int64InstProvider) lookupUpDownCounter(kind sdkmetric.InstrumentKind, name, desc string, u string) (int64UpDownCounter, error) {
+	return int64UpDownCounter{upDownCounter: newUpDownCounter[int64](p.impl(kind, name, desc, u))}, nil
 }
 
 // float64InstProvider provides all OpenTelemetry instruments.
@@ -354,16 +379,40 @@ func newFloat64InstProvider(p *MeterProvider, pipes *pipeline, s instrumentation
 	return &float64InstProvider{provider: p, pipes: pipes, scope: s}
 }
 
-// lookup returns the resolved instrumentImpl.
-func (p *float64InstProvider) lookup(kind sdkmetric.InstrumentKind, name, desc string, u string) (*float64Inst, error) {
-	i := sdkmetric.Instrument{
+// instrument returns the sdkmetric.Instrument describing an instrument.
+func (p *float64InstProvider) instrument(kind sdkmetric.InstrumentKind, name, desc string, u string) sdkmetric.Instrument {
+	return sdkmetric.Instrument{
 		Name:        name,
 		Description: "", // TODO
 		Unit:        u,
 		Kind:        kind,
 		Scope:       p.scope,
 	}
-	return &float64Inst{provider: p.provider, instrument: i}, nil
+}
+
+// impl returns the resolved instrumentImpl.
+func (p *float64InstProvider) impl(kind sdkmetric.InstrumentKind, name, desc string, u string) *instrumentImpl {
+	return p.provider.instrumentImpl(p.instrument(kind, name, desc, u))
+}
+
+// lookup returns the resolved counter instrument.
+func (p *float64InstProvider) lookup(kind sdkmetric.InstrumentKind, name, desc string, u string) (*float64Inst, error) {
+	return &float64Inst{instrumentImpl: p.impl(kind, name, desc, u)}, nil
+}
+
+// lookupGauge returns the resolved gauge instrument.
+func (p *float64InstProvider) lookupGauge(kind sdkmetric.InstrumentKind, name, desc string, u string) (*float64Gauge, error) {
+	return &float64Gauge{instrumentImpl: p.impl(kind, name, desc, u)}, nil
+}
+
+// lookupHistogram returns the resolved histogram instrument.
+func (p *float64InstProvider) lookupHistogram(kind sdkmetric.InstrumentKind, name, desc string, u string) (float64Histogram, error) {
+	return float64Histogram{histogram: newHistogram[float64](p.impl(kind, name, desc, u))}, nil
+}
+
+// lookupUpDownCounter returns the resolved up-down counter instrument.
+func (p *float64InstProvider) lookupUpDownCounter(kind sdkmetric.InstrumentKind, name, desc string, u string) (float64UpDownCounter, error) {
+	return float64UpDownCounter{upDownCounter: newUpDownCounter[float64](p.impl(kind, name, desc, u))}, nil
 }
 
 type int64ObservProvider struct{ *int64InstProvider }
@@ -373,7 +422,7 @@ func (p int64ObservProvider) lookup(kind sdkmetric.InstrumentKind, name, desc st
 }
 
 func (p int64ObservProvider) registerCallbacks(inst int64Observable, cBacks []metric.Int64Callback) {
-	if inst.observable == nil {
+	if inst.observable == nil || inst.view.Drop {
 		// Drop.
 		return
 	}
@@ -404,7 +453,7 @@ func (p float64ObservProvider) lookup(kind sdkmetric.InstrumentKind, name, desc
 }
 
 func (p float64ObservProvider) registerCallbacks(inst float64Observable, cBacks []metric.Float64Callback) {
-	if inst.observable == nil {
+	if inst.observable == nil || inst.view.Drop {
 		// Drop aggregator.
 		return
 	}
@@ -8,11 +8,13 @@ import (
 	"testing"
 
 	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+	"github.com/cactus/go-statsd-client/v5/statsd"
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
 
 	"go.opentelemetry.io/otel/metric"
@@ -21,7 +23,7 @@ import (
 // A meter should be able to make instruments concurrently.
 func TestMeterInstrumentConcurrency(t *testing.T) {
 	wg := &sync.WaitGroup{}
-	wg.Add(12)
+	wg.Add(14)
 
 	m := NewMeterProvider().Meter("inst-concurrency")
 
@@ -57,6 +59,10 @@ func TestMeterInstrumentConcurrency(t *testing.T) {
 		_, _ = m.Float64UpDownCounter("SFUpDownCounter")
 		wg.Done()
 	}()
+	go func() {
+		_, _ = m.Float64Gauge("SFGauge")
+		wg.Done()
+	}()
 	go func() {
 		_, _ = m.Float64Histogram("SFHistogram")
 		wg.Done()
@@ -69,6 +75,10 @@ func TestMeterInstrumentConcurrency(t *testing.T) {
 		_, _ = m.Int64UpDownCounter("SIUpDownCounter")
 		wg.Done()
 	}()
+	go func() {
+		_, _ = m.Int64Gauge("SIGauge")
+		wg.Done()
+	}()
 	go func() {
 		_, _ = m.Int64Histogram("SIHistogram")
 		wg.Done()
@@ -171,13 +181,7 @@ func TestMeterCreatesInstruments(t *testing.T) {
 				{
 					Method: "Inc",
 					S:      "aint",
-					I:      3,
-					F:      1.0,
-				},
-				{
-					Method: "Inc",
-					S:      "aint",
-					I:      4,
+					I:      7,
 					F:      1.0,
 				},
 			},
@@ -199,15 +203,9 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
-					S:      "aint",
-					I:      11,
-					F:      1.0,
-				},
-				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "aint",
-					I:      4,
+					I:      15,
 					F:      1.0,
 				},
 			},
@@ -229,13 +227,13 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "agauge",
 					I:      11,
 					F:      1.0,
 				},
 				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "agauge",
 					I:      4,
 					F:      1.0,
@@ -261,13 +259,7 @@ func TestMeterCreatesInstruments(t *testing.T) {
 				{
 					Method: "Inc",
 					S:      "afloat",
-					I:      3,
-					F:      1.0,
-				},
-				{
-					Method: "Inc",
-					S:      "afloat",
-					I:      4,
+					I:      7,
 					F:      1.0,
 				},
 			},
@@ -289,15 +281,9 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "afloat",
-					I:      11,
-					F:      1.0,
-				},
-				{
-					Method: "Inc",
-					S:      "afloat",
-					I:      4,
+					I:      15,
 					F:      1.0,
 				},
 			},
@@ -319,13 +305,13 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "agauge",
 					I:      11,
 					F:      1.0,
 				},
 				{
-					Method: "Inc",
+					Method: "Gauge",
 					S:      "agauge",
 					I:      4,
 					F:      1.0,
@@ -359,7 +345,7 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
+					Method: "GaugeDelta",
 					S:      "sint",
 					I:      11,
 					F:      1.0,
@@ -383,6 +369,23 @@ func TestMeterCreatesInstruments(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "SyncInt64Gauge",
+			fn: func(t *testing.T, m metric.Meter) {
+				gauge, err := m.Int64Gauge("sgauge")
+				assert.NoError(t, err)
+
+				gauge.Record(context.Background(), 5)
+			},
+			want: []mocks.MockStatSenderMethod{
+				{
+					Method: "Gauge",
+					S:      "sgauge",
+					I:      5,
+					F:      1.0,
+				},
+			},
+		},
 		{
 			name: "SyncFloat64Count",
 			fn: func(t *testing.T, m metric.Meter) {
@@ -410,7 +413,7 @@ func TestMeterCreatesInstruments(t *testing.T) {
 			},
 			want: []mocks.MockStatSenderMethod{
 				{
-					Method: "Inc",
+					Method: "GaugeDelta",
 					S:      "sfloat",
 					I:      11,
 					F:      1.0,
@@ -434,6 +437,59 @@ func TestMeterCreatesInstruments(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "SyncFloat64Gauge",
+			fn: func(t *testing.T, m metric.Meter) {
+				gauge, err := m.Float64Gauge("sgauge")
+				assert.NoError(t, err)
+
+				gauge.Record(context.Background(), 9)
+			},
+			want: []mocks.MockStatSenderMethod{
+				{
+					Method: "Gauge",
+					S:      "sgauge",
+					I:      9,
+					F:      1.0,
+				},
+			},
+		},
+		{
+			name: "SyncFloat64FractionalValues",
+			fn: func(t *testing.T, m metric.Meter) {
+				ctr, err := m.Float64Counter("sfloat")
+				assert.NoError(t, err)
+				ctr.Add(context.Background(), 0.5)
+
+				hist, err := m.Float64Histogram("histogram")
+				assert.NoError(t, err)
+				hist.Record(context.Background(), 0.75)
+
+				gauge, err := m.Float64Gauge("sgauge")
+				assert.NoError(t, err)
+				gauge.Record(context.Background(), 1.25)
+			},
+			want: []mocks.MockStatSenderMethod{
+				{
+					Method: "Raw",
+					S:      "sfloat",
+					S2:     "0.5|c",
+					F:      1.0,
+				},
+				{
+					Method: "Raw",
+					S:      "histogram",
+					S2:     "0.75|ms",
+					F:      1.0,
+				},
+				{
+					Method: "Raw",
+					S:      "sgauge",
+					S2:     "1.25|g",
+					F:      1.0,
+				},
+			},
+		},
 	}
 
 	for _, tt := range testCases {
@@ -449,9 +505,7 @@ func TestMeterCreatesInstruments(t *testing.T) {
 
 			tt.fn(t, m)
 
-			err = mp.produce(ctx)
-			require.NoError(t, err)
-
+			// Stop calls the observable callbacks.
 			err = mp.Stop(ctx)
 			require.NoError(t, err)
 
@@ -857,3 +911,84 @@ func BenchmarkInstrumentCreation(b *testing.B) {
 		sfHistogram, _ = meter.Float64Histogram("sync.float64.histogram")
 	}
 }
+
+// discardStatSender drops all metrics, so benchmarks only measure the
+// MeterProvider.
+type discardStatSender struct {
+	StatSender
+}
+
+func (discardStatSender) IncFloat(string, float64, float32, ...statsd.Tag) error        { return nil }
+func (discardStatSender) GaugeFloat(string, float64, float32, ...statsd.Tag) error      { return nil }
+func (discardStatSender) GaugeFloatDelta(string, float64, float32, ...statsd.Tag) error { return nil }
+func (discardStatSender) TimingFloat(string, float64, float32, ...statsd.Tag) error     { return nil }
+
+func BenchmarkCounterAdd(b *testing.B) {
+	ctx := context.Background()
+	// The options slice is built once, as passing options to an interface
+	// method makes it escape.
+	opts := []metric.AddOption{metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.Int("http.status_code", 200),
+	))}
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Default"},
+		{name: "Aggregation", opts: []Option{WithAggregation(true)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			mp := NewMeterProvider(append(bc.opts, WithStatsdClient(discardStatSender{}))...)
+			ctr, err := mp.Meter("BenchmarkCounterAdd").Int64Counter("requests")
+			require.NoError(b, err)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				ctr.Add(ctx, 1, opts...)
+			}
+		})
+	}
+}
+
+func BenchmarkHistogramRecord(b *testing.B) {
+	ctx := context.Background()
+	opts := []metric.RecordOption{metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.Int("http.status_code", 200),
+	))}
+
+	mp := NewMeterProvider(WithStatsdClient(discardStatSender{}))
+	hist, err := mp.Meter("BenchmarkHistogramRecord").Float64Histogram("duration", metric.WithUnit("s"))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		hist.Record(ctx, 0.25, opts...)
+	}
+}
+
+func TestMeasurementDoesNotAllocate(t *testing.T) {
+	ctx := context.Background()
+	set := attribute.NewSet(attribute.String("k", "v"))
+	addOpts := []metric.AddOption{metric.WithAttributeSet(set)}
+	recordOpts := []metric.RecordOption{metric.WithAttributeSet(set)}
+
+	mp := NewMeterProvider(WithStatsdClient(discardStatSender{}))
+	m := mp.Meter("TestMeasurementDoesNotAllocate")
+	ctr, err := m.Int64Counter("counter")
+	require.NoError(t, err)
+	hist, err := m.Float64Histogram("histogram")
+	require.NoError(t, err)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ctr.Add(ctx, 1, addOpts...)
+		hist.Record(ctx, 1.5, recordOpts...)
+	})
+	assert.Zero(t, allocs)
+}
@@ -212,7 +212,7 @@ func (m *MockStatSender) SetInt(s string, i int64, f float32, tag ...statsd.Tag)
 
 func (m *MockStatSender) Raw(s string, s2 string, f float32, tag ...statsd.Tag) error {
 	m.addOutput(&MockStatSenderMethod{
-		Method: "Set",
+		Method: "Raw",
 		S:      s,
 		S2:     s2,
 		F:      f,
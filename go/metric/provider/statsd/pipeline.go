@@ -32,6 +32,37 @@ type pipeline struct {
 	sync.Mutex
 	callbacks      []func(context.Context) error
 	multiCallbacks list.List
+
+	// observed are the observables to flush once the callbacks returned. It
+	// is guarded by observedMu, as the callbacks add to it.
+	observedMu sync.Mutex
+	observed   []observationFlusher
+}
+
+// observationFlusher is implemented by the observables sending their
+// observations once all the callbacks of a collection returned.
+type observationFlusher interface {
+	flushObservations()
+}
+
+// addObserved registers o to be flushed once the callbacks being run by
+// `produce()` returned.
+func (p *pipeline) addObserved(o observationFlusher) {
+	p.observedMu.Lock()
+	defer p.observedMu.Unlock()
+	p.observed = append(p.observed, o)
+}
+
+// flushObserved flushes the observables registered with addObserved.
+func (p *pipeline) flushObserved() {
+	p.observedMu.Lock()
+	observed := p.observed
+	p.observed = nil
+	p.observedMu.Unlock()
+
+	for _, o := range observed {
+		o.flushObservations()
+	}
 }
 
 // addCallback registers a single instrument callback to be run when
@@ -63,6 +94,7 @@ func (p *pipeline) addMultiCallback(c multiCallback) (unregister func()) {
 func (p *pipeline) produce(ctx context.Context) error {
 	p.Lock()
 	defer p.Unlock()
+	defer p.flushObserved()
 
 	var errs multierror
 	for _, c := range p.callbacks {
@@ -2,58 +2,140 @@ package statsd
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cactus/go-statsd-client/v5/statsd"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/embedded"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 // Default periodic reader timing.
 const (
-	defaultInterval = time.Millisecond * 60000
+	defaultInterval            = time.Millisecond * 60000
+	defaultBackpressureTimeout = time.Millisecond * 10
 )
 
+// defaultAddress is the address of the default transport.
+const defaultAddress = "127.0.0.1:8125"
+
 type MeterProvider struct {
 	embedded.MeterProvider
 
 	scopes sync.Map
 	pipes  *pipeline
 
-	statsdClient statsd.StatSender
+	// destinations are where the stats are sent, the first one being
+	// configured by the options of the MeterProvider.
+	destinations []*destination
 	resource     *resource.Resource
 
-	interval time.Duration
+	interval                    time.Duration
+	upDownCounterMode           UpDownCounterMode
+	observableUpDownCounterMode UpDownCounterMode
+	histogramType               HistogramType
+	instrumentHistogramTypes    map[string]HistogramType
+	sampleRate                  float32
+	instrumentSampleRates       map[string]float32
+	prefix                      string
+	scopeMode                   ScopeMode
+	scopeVersion                bool
+	sanitizer                   Sanitizer
+	resourceTags                []statsd.Tag
+	views                       []View
+	cardinalityLimit            int
+
+	// upDownTotals holds the *valueMap of the running totals of each up-down
+	// counter sent with UpDownCounterModeAbsolute, by numberInstrumentID.
+	upDownTotals sync.Map
+	// instruments holds the *instrumentImpl of each synchronous instrument,
+	// by instrumentID, so that its stream, aggregated series and cardinality
+	// limit are shared by all its lookups.
+	instruments sync.Map
+
+	// overflows counts the measurements folded into the overflow attribute
+	// set.
+	overflows atomic.Int64
 
-	done         chan struct{}
-	cancel       context.CancelFunc
-	shutdownOnce sync.Once
+	// aggregator is nil unless aggregation is enabled.
+	aggregator *aggregator
+
+	// lifecycle serializes Start, Stop and Shutdown, and guards done and
+	// cancel, which is nil unless the run loop is started.
+	lifecycle sync.Mutex
+	done      chan struct{}
+	cancel    context.CancelFunc
+	shutdown  atomic.Bool
 }
 
 var _ metric.MeterProvider = &MeterProvider{}
 
+var (
+	errStarted  = errors.New("meter provider already started")
+	errShutdown = errors.New("meter provider shut down")
+)
+
+// flusher is implemented by clients buffering stats.
+type flusher interface {
+	Flush() error
+}
+
+// contextFlusher is implemented by the transports that can stop flushing
+// once a context is done, like TCPTransport.
+type contextFlusher interface {
+	FlushContext(ctx context.Context) error
+}
+
+// Stats are statistics about the measurements of a MeterProvider.
+type Stats struct {
+	// Overflows is the number of measurements folded into the overflow
+	// attribute set because of the cardinality limit.
+	Overflows int64
+	// Dropped is the number of stats dropped by the workers of all the
+	// destinations because of the backpressure policy.
+	Dropped int64
+	// UDS are the error counters of the unix domain socket transport, if
+	// used by the first destination.
+	UDS UDSStats
+	// TCP are the counters of the TCP transport, if used by the first
+	// destination.
+	TCP TCPStats
+}
+
+// NewMeterProvider returns a MeterProvider configured by the environment
+// variables, then by opts. The environment variables are:
+//   - OTEL_STATSD_ENDPOINT, the endpoint as accepted by WithEndpoint,
+//   - OTEL_STATSD_PROTOCOL, the scheme of an endpoint without one: udp, tcp,
+//     unix or unixgram,
+//   - OTEL_STATSD_PREFIX, as set by WithPrefix,
+//   - OTEL_METRIC_EXPORT_INTERVAL, the interval in milliseconds,
+//   - OTEL_STATSD_WORKERS, as set by WithWorkers,
+//   - OTEL_STATSD_TAG_FORMAT: dogstatsd, influx, graphite or etsy,
+//   - OTEL_STATSD_MAX_PACKET_SIZE, as set by WithBatching.
+//
+// Invalid values are reported with otel.Handle and ignored.
 func NewMeterProvider(opts ...Option) *MeterProvider {
 	c := config{
-		Interval: defaultInterval,
+		Interval:                    defaultInterval,
+		Endpoint:                    defaultAddress,
+		BackpressureTimeout:         defaultBackpressureTimeout,
+		HistogramType:               HistogramTypeTiming,
+		SampleRate:                  1.0,
+		Sanitizer:                   NewDefaultSanitizer(),
+		ObservableUpDownCounterMode: UpDownCounterModeAbsolute,
 	}
+	c = applyEnv(c)
 	for _, opt := range opts {
 		c = opt.apply(c)
 	}
-	statsdClient := c.StatsdClient
-	if statsdClient == nil {
-		var err error
-		statsdClient, err = statsd.NewClientWithConfig(&statsd.ClientConfig{
-			Address:     "127.0.0.1:8125",
-			UseBuffered: false,
-		})
-		if err != nil {
-			otel.Handle(err)
-		}
-	}
 
 	if c.Resource == nil {
 		c.Resource = resource.Default()
@@ -66,17 +148,63 @@ func NewMeterProvider(opts ...Option) *MeterProvider {
 	}
 
 	ret := &MeterProvider{
-		pipes:    newPipeline(c.Resource),
-		resource: c.Resource,
-		interval: c.Interval,
+		pipes:                       newPipeline(c.Resource),
+		resource:                    c.Resource,
+		interval:                    c.Interval,
+		upDownCounterMode:           c.UpDownCounterMode,
+		observableUpDownCounterMode: c.ObservableUpDownCounterMode,
+		histogramType:               c.HistogramType,
+		instrumentHistogramTypes:    c.InstrumentHistogramTypes,
+		sampleRate:                  c.SampleRate,
+		instrumentSampleRates:       c.InstrumentSampleRates,
+		prefix:                      strings.TrimSuffix(c.Prefix, "."),
+		scopeMode:                   c.ScopeMode,
+		scopeVersion:                c.ScopeVersion,
+		sanitizer:                   c.Sanitizer,
+		views:                       c.Views,
+		cardinalityLimit:            c.CardinalityLimit,
 	}
 
-	if c.Workers > 0 {
-		workers := newWorkerStatSender(c.Workers, c.WorkerChanBufferSize, statsdClient)
-		statsdClient = workers
+	if !c.DisableResourceTags {
+		ret.resourceTags = ret.collectResourceTags(c.ResourceTagFilters, c.ResourceTagRenames)
+	}
+
+	if c.Aggregation {
+		ret.aggregator = newAggregator(ret)
+	}
+
+	var primary *destination
+	if c.StatsdClient != nil {
+		primary = newClientDestination(c.StatsdClient)
+	} else {
+		var tcpOpts []TCPOption
+		if c.TLSConfig != nil {
+			tcpOpts = append(tcpOpts, WithTCPTLSConfig(c.TLSConfig))
+		}
+		if c.ConnectionStateCallback != nil {
+			tcpOpts = append(tcpOpts, WithTCPStateCallback(c.ConnectionStateCallback))
+		}
+		primary = newTransportDestination(c.Transport, c.Endpoint, tcpOpts, c.TagFormat, c.BatchSize, c.BatchInterval)
+	}
+	ret.destinations = append(ret.destinations, primary)
+	for _, d := range c.Destinations {
+		ret.destinations = append(ret.destinations, newDestination(d, c))
+	}
+	if c.Workers > 0 || len(c.Destinations) > 0 {
+		for _, d := range ret.destinations {
+			d.withWorkers(c)
+		}
+	}
+	if len(c.Destinations) > 0 {
+		// The stats are sent without Start, as they are without
+		// destinations.
+		for _, d := range ret.destinations {
+			if err := d.start(); err != nil {
+				otel.Handle(err)
+			}
+		}
 	}
 
-	ret.statsdClient = statsdClient
 	return ret
 }
 
@@ -91,9 +219,21 @@ func (c *MeterProvider) Meter(instrumentationName string, opts ...metric.MeterOp
 	return newMeter(c, scope, c.pipes)
 }
 
+// Start starts the workers and the periodic collection of the observable
+// instruments. It can be called again after Stop, but not after Shutdown.
 func (c *MeterProvider) Start(_ context.Context) error {
-	if w, ok := c.statsdClient.(*workerStatSender); ok {
-		err := w.Start()
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if c.shutdown.Load() {
+		return errShutdown
+	}
+	if c.cancel != nil {
+		return errStarted
+	}
+
+	for _, d := range c.destinations {
+		err := d.start()
 		if err != nil {
 			return err
 		}
@@ -104,34 +244,306 @@ func (c *MeterProvider) Start(_ context.Context) error {
 	c.cancel = cancel
 	c.done = make(chan struct{})
 
-	go func() {
-		defer func() { close(c.done) }()
+	go func(done chan struct{}) {
+		defer func() { close(done) }()
 		c.run(ctx, c.interval)
-	}()
+	}(c.done)
 
 	return nil
 }
 
-func (c *MeterProvider) Stop(_ context.Context) error {
-	var err error = nil
+// Stop stops the periodic collection, flushes the measurements like
+// ForceFlush and stops the workers. The stats recorded with workers are
+// rejected until Start is called again. It returns ctx.Err() if ctx is done
+// before the stats are sent, in which case the stats left are dropped.
+func (c *MeterProvider) Stop(ctx context.Context) error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
 
-	c.shutdownOnce.Do(func() {
-		// Stop the run loop.
-		c.cancel()
-		<-c.done
+	if c.shutdown.Load() {
+		return nil
+	}
+	return c.stop(ctx)
+}
 
-		if w, ok := c.statsdClient.(*workerStatSender); ok {
-			err = w.Stop()
+// ForceFlush calls the observable callbacks, sends the aggregated
+// measurements and waits for the workers to send the queued stats, then
+// flushes the transport or the StatsD client if it buffers stats, see
+// WithStatsdClient. It returns ctx.Err() if ctx is done before the stats are
+// sent.
+//
+// It does nothing after Shutdown.
+func (c *MeterProvider) ForceFlush(ctx context.Context) error {
+	if c.shutdown.Load() {
+		return nil
+	}
+	return c.flush(ctx)
+}
+
+// Shutdown stops the MeterProvider like Stop, and closes the StatsD client
+// if it was created by the MeterProvider. It returns ctx.Err() if ctx is done
+// before the stats are sent, in which case the stats left are dropped and the
+// workers and the transports are stopped anyway.
+//
+// Instruments do nothing after Shutdown. Only the first call shuts the
+// MeterProvider down, the others return nil.
+func (c *MeterProvider) Shutdown(ctx context.Context) error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	if c.shutdown.Swap(true) {
+		return nil
+	}
+
+	err := c.stop(ctx)
+	for _, d := range c.destinations {
+		if cerr := d.close(); err == nil {
+			err = cerr
 		}
-	})
+	}
+	return err
+}
 
+// stop stops the run loop, unless it is not started, flushes the
+// measurements and stops the workers. The workers are stopped even if ctx is
+// done first. c.lifecycle must be held.
+func (c *MeterProvider) stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+		}
+	}
+
+	err := c.flush(ctx)
+	for _, d := range c.destinations {
+		if serr := d.stop(ctx); err == nil {
+			err = serr
+		}
+	}
 	return err
 }
 
+// flush collects the measurements and waits for them to be sent.
+func (c *MeterProvider) flush(ctx context.Context) error {
+	err := c.collect(ctx)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	// Flush every destination, even if one fails.
+	err = nil
+	for _, d := range c.destinations {
+		if ferr := d.flush(ctx); err == nil {
+			err = ferr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// instrumentImpl returns the resolved synchronous instrument i, resolving it
+// on its first lookup.
+func (c *MeterProvider) instrumentImpl(i sdkmetric.Instrument) *instrumentImpl {
+	id := newInstrumentID(i)
+	if impl, ok := c.instruments.Load(id); ok {
+		return impl.(*instrumentImpl)
+	}
+	impl, _ := c.instruments.LoadOrStore(id, newInstrumentImpl(c, i))
+	return impl.(*instrumentImpl)
+}
+
+// viewOf returns the Stream of the first view matching the instrument i.
+func (c *MeterProvider) viewOf(i sdkmetric.Instrument) Stream {
+	for _, v := range c.views {
+		if s, ok := v(i); ok {
+			return s
+		}
+	}
+	return Stream{Name: i.Name}
+}
+
+// newStream returns the stream the instrument i is sent as with view.
+func (c *MeterProvider) newStream(i sdkmetric.Instrument, view Stream) *stream {
+	ret := &stream{name: view.Name}
+
+	switch c.scopeMode {
+	case ScopeModePrefix:
+		if i.Scope.Name != "" {
+			prefix := i.Scope.Name
+			if c.scopeVersion && i.Scope.Version != "" {
+				prefix += "." + strings.ReplaceAll(i.Scope.Version, ".", "_")
+			}
+			ret.name = prefix + "." + ret.name
+		}
+	case ScopeModeTags:
+		if i.Scope.Name != "" {
+			ret.tags = append(ret.tags, statsd.Tag{scopeNameTag, i.Scope.Name})
+		}
+		if c.scopeVersion && i.Scope.Version != "" {
+			ret.tags = append(ret.tags, statsd.Tag{scopeVersionTag, i.Scope.Version})
+		}
+	}
+
+	if c.prefix != "" {
+		ret.name = c.prefix + "." + ret.name
+	}
+
+	ret.name = c.sanitizeName(ret.name)
+	for j := range ret.tags {
+		ret.tags[j] = c.sanitizeTag(ret.tags[j][0], ret.tags[j][1])
+	}
+	return ret
+}
+
+// collectResourceTags returns the resource attributes kept by all filters as
+// tags, renamed by renames.
+func (c *MeterProvider) collectResourceTags(filters []attribute.Filter, renames map[attribute.Key]string) []statsd.Tag {
+	var ret []statsd.Tag
+
+	iter := c.resource.Iter()
+attrs:
+	for iter.Next() {
+		attr := iter.Attribute()
+		for _, f := range filters {
+			if !f(attr) {
+				continue attrs
+			}
+		}
+		key := string(attr.Key)
+		if to, ok := renames[attr.Key]; ok {
+			key = to
+		}
+		ret = append(ret, c.sanitizeTag(key, attr.Value.Emit()))
+	}
+
+	return ret
+}
+
+// sanitizeName returns name sanitized by the Sanitizer, if any.
+func (c *MeterProvider) sanitizeName(name string) string {
+	if c.sanitizer == nil {
+		return name
+	}
+	return c.sanitizer.Name(name)
+}
+
+// sanitizeTag returns the tag made of key and value sanitized by the
+// Sanitizer, if any.
+func (c *MeterProvider) sanitizeTag(key, value string) statsd.Tag {
+	if c.sanitizer == nil {
+		return statsd.Tag{key, value}
+	}
+	return statsd.Tag{c.sanitizer.TagKey(key), c.sanitizer.TagValue(value)}
+}
+
+// sampleRateOf returns the sample rate of the instrument i with view.
+func (c *MeterProvider) sampleRateOf(i sdkmetric.Instrument, view Stream) float32 {
+	if view.SampleRate > 0 {
+		return view.SampleRate
+	}
+	if rate, ok := c.instrumentSampleRates[i.Name]; ok {
+		return rate
+	}
+	return c.sampleRate
+}
+
+// cardinalityLimitOf returns the cardinality limit of the instrument with
+// view, or 0 if there is no limit.
+func (c *MeterProvider) cardinalityLimitOf(view Stream) int {
+	limit := view.CardinalityLimit
+	if limit == 0 {
+		limit = c.cardinalityLimit
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// Stats returns statistics about the measurements of the MeterProvider.
+func (c *MeterProvider) Stats() Stats {
+	ret := Stats{
+		Overflows: c.overflows.Load(),
+	}
+	for _, d := range c.destinations {
+		if d.workers != nil {
+			ret.Dropped += d.workers.dropped.Load()
+		}
+	}
+	switch t := c.destinations[0].base.(type) {
+	case *UDSTransport:
+		ret.UDS = t.Stats()
+	case *TCPTransport:
+		ret.TCP = t.Stats()
+	}
+	return ret
+}
+
+// histogramTypeOf returns the StatsD type the histogram i is sent as with
+// view.
+func (c *MeterProvider) histogramTypeOf(i sdkmetric.Instrument, view Stream) HistogramType {
+	t := view.HistogramType
+	if t == 0 {
+		var ok bool
+		if t, ok = c.instrumentHistogramTypes[i.Name]; !ok {
+			t = c.histogramType
+		}
+	}
+	if t == HistogramTypeAuto {
+		if _, ok := durationUnitToMillis(i.Unit); ok {
+			return HistogramTypeTiming
+		}
+		return HistogramTypeHistogram
+	}
+	return t
+}
+
+// record sends a measurement of a synchronous instrument, or aggregates it
+// if aggregation is enabled.
+func (c *MeterProvider) record(stat statType, s *stream, val float64, rate float32, attrs attribute.Set) {
+	if c.shutdown.Load() {
+		return
+	}
+	if c.aggregator != nil {
+		c.aggregator.record(stat, s, val, rate, attrs)
+		return
+	}
+	c.send(stat, s.name, val, rate, collectTags(c, s, attrs))
+}
+
+// send sends val to the destinations as a metric of type stat.
+func (c *MeterProvider) send(stat statType, name string, val float64, rate float32, tags []statsd.Tag) {
+	for _, d := range c.destinations {
+		_ = d.send(stat, name, val, rate, tags)
+	}
+}
+
 func (c *MeterProvider) produce(ctx context.Context) error {
 	return c.pipes.produce(ctx)
 }
 
+// collect calls all observables, flushes the aggregated instruments and
+// resets the cardinality limits.
+func (c *MeterProvider) collect(ctx context.Context) error {
+	err := c.produce(ctx)
+	if c.aggregator != nil {
+		c.aggregator.flush()
+	}
+	c.instruments.Range(func(_, impl any) bool {
+		if l := impl.(*instrumentImpl).limiter; l != nil {
+			l.reset()
+		}
+		return true
+	})
+	return err
+}
+
 // newTicker allows testing override.
 var newTicker = time.NewTicker
 
@@ -144,7 +556,7 @@ func (c *MeterProvider) run(ctx context.Context, interval time.Duration) {
 	for {
 		select {
 		case <-ticker.C:
-			err := c.produce(ctx)
+			err := c.collect(ctx)
 			if err != nil {
 				otel.Handle(err)
 			}
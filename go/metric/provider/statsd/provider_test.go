@@ -2,14 +2,19 @@ package statsd
 
 import (
 	"context"
+	"net"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
 	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 func TestProvider(t *testing.T) {
@@ -157,3 +162,680 @@ func TestProviderRun(t *testing.T) {
 	// assert
 	rs.CHECK(t)
 }
+
+func TestProviderUpDownCounterMode(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode UpDownCounterMode
+		want []mocks.MockStatSenderMethod
+	}{
+		{
+			name: "Delta",
+			mode: UpDownCounterModeDelta,
+			want: []mocks.MockStatSenderMethod{
+				{Method: "GaugeDelta", S: "queue.depth", I: 5, F: 1.0, Tags: []statsd.Tag{{"q", "a"}}},
+				{Method: "GaugeDelta", S: "queue.depth", I: -2, F: 1.0, Tags: []statsd.Tag{{"q", "a"}}},
+				{Method: "GaugeDelta", S: "queue.depth", I: 3, F: 1.0, Tags: []statsd.Tag{{"q", "b"}}},
+			},
+		},
+		{
+			name: "Absolute",
+			mode: UpDownCounterModeAbsolute,
+			want: []mocks.MockStatSenderMethod{
+				{Method: "Gauge", S: "queue.depth", I: 5, F: 1.0, Tags: []statsd.Tag{{"q", "a"}}},
+				{Method: "Gauge", S: "queue.depth", I: 3, F: 1.0, Tags: []statsd.Tag{{"q", "a"}}},
+				{Method: "Gauge", S: "queue.depth", I: 3, F: 1.0, Tags: []statsd.Tag{{"q", "b"}}},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(tt.want...)
+
+			mp := NewMeterProvider(WithStatsdClient(rs), WithUpDownCounterMode(tt.mode))
+			ctr, err := mp.Meter("").Int64UpDownCounter("queue.depth")
+			require.NoError(t, err)
+
+			ctr.Add(ctx, 5, metric.WithAttributes(attribute.String("q", "a")))
+			ctr.Add(ctx, -2, metric.WithAttributes(attribute.String("q", "a")))
+			ctr.Add(ctx, 3, metric.WithAttributes(attribute.String("q", "b")))
+
+			rs.CHECK(t)
+		})
+	}
+}
+
+func TestProviderUpDownCounterAbsoluteLookups(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "queue.depth", I: 5, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "queue.depth", I: 6, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "queue.depth", I: 1, F: 1.0},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithUpDownCounterMode(UpDownCounterModeAbsolute))
+
+	// Each lookup of the instrument adds to the same total.
+	a, err := mp.Meter("x").Int64UpDownCounter("queue.depth")
+	require.NoError(t, err)
+	b, err := mp.Meter("x").Int64UpDownCounter("queue.depth")
+	require.NoError(t, err)
+	f, err := mp.Meter("x").Float64UpDownCounter("queue.depth")
+	require.NoError(t, err)
+
+	a.Add(ctx, 5)
+	b.Add(ctx, 1)
+	f.Add(ctx, 1)
+
+	rs.CHECK(t)
+}
+
+func TestProviderObservableCounterDelta(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 10, F: 1.0, Tags: []statsd.Tag{{"code", "200"}}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 2, F: 1.0, Tags: []statsd.Tag{{"code", "500"}}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 5, F: 1.0, Tags: []statsd.Tag{{"code", "200"}}},
+		// Counter reset.
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 4, F: 1.0, Tags: []statsd.Tag{{"code", "200"}}},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs))
+
+	values := [][2]int64{{10, 2}, {15, 2}, {15, 2}, {4, 2}}
+	cycle := 0
+	cback := func(_ context.Context, o metric.Int64Observer) error {
+		o.Observe(values[cycle][0], metric.WithAttributes(attribute.String("code", "200")))
+		o.Observe(values[cycle][1], metric.WithAttributes(attribute.String("code", "500")))
+		return nil
+	}
+	_, err := mp.Meter("").Int64ObservableCounter("requests", metric.WithInt64Callback(cback))
+	require.NoError(t, err)
+
+	for cycle = range values {
+		require.NoError(t, mp.produce(ctx))
+	}
+
+	rs.CHECK(t)
+}
+
+func TestProviderObservableUpDownCounterMode(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		want []mocks.MockStatSenderMethod
+	}{
+		{
+			name: "Absolute",
+			want: []mocks.MockStatSenderMethod{
+				{Method: "Gauge", S: "inflight", I: 5, F: 1.0},
+				{Method: "Gauge", S: "inflight", I: 3, F: 1.0},
+				{Method: "Gauge", S: "inflight", I: 3, F: 1.0},
+			},
+		},
+		{
+			name: "Delta",
+			opts: []Option{WithObservableUpDownCounterMode(UpDownCounterModeDelta)},
+			want: []mocks.MockStatSenderMethod{
+				{Method: "GaugeDelta", S: "inflight", I: 5, F: 1.0},
+				{Method: "GaugeDelta", S: "inflight", I: -2, F: 1.0},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(tt.want...)
+
+			mp := NewMeterProvider(append(tt.opts, WithStatsdClient(rs))...)
+
+			values := []int64{5, 3, 3}
+			cycle := 0
+			cback := func(_ context.Context, o metric.Int64Observer) error {
+				o.Observe(values[cycle])
+				return nil
+			}
+			_, err := mp.Meter("").Int64ObservableUpDownCounter("inflight", metric.WithInt64Callback(cback))
+			require.NoError(t, err)
+
+			for cycle = range values {
+				require.NoError(t, mp.produce(ctx))
+			}
+
+			rs.CHECK(t)
+		})
+	}
+}
+
+func TestProviderHistogramType(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		unit string
+		want mocks.MockStatSenderMethod
+	}{
+		{
+			name: "DefaultTiming",
+			want: mocks.MockStatSenderMethod{Method: "Raw", S: "latency", S2: "0.25|ms", F: 1.0},
+		},
+		{
+			name: "TimingSecondsToMillis",
+			unit: "s",
+			want: mocks.MockStatSenderMethod{Method: "Timing", S: "latency", I: 250, F: 1.0},
+		},
+		{
+			name: "Histogram",
+			opts: []Option{WithHistogramType(HistogramTypeHistogram)},
+			unit: "s",
+			want: mocks.MockStatSenderMethod{Method: "Raw", S: "latency", S2: "0.25|h", F: 1.0},
+		},
+		{
+			name: "Distribution",
+			opts: []Option{WithInstrumentHistogramType("latency", HistogramTypeDistribution)},
+			want: mocks.MockStatSenderMethod{Method: "Raw", S: "latency", S2: "0.25|d", F: 1.0},
+		},
+		{
+			name: "InstrumentOverridesGlobal",
+			opts: []Option{
+				WithHistogramType(HistogramTypeDistribution),
+				WithInstrumentHistogramType("latency", HistogramTypeTiming),
+			},
+			unit: "s",
+			want: mocks.MockStatSenderMethod{Method: "Timing", S: "latency", I: 250, F: 1.0},
+		},
+		{
+			name: "AutoDuration",
+			opts: []Option{WithHistogramType(HistogramTypeAuto)},
+			unit: "s",
+			want: mocks.MockStatSenderMethod{Method: "Timing", S: "latency", I: 250, F: 1.0},
+		},
+		{
+			name: "AutoBytes",
+			opts: []Option{WithHistogramType(HistogramTypeAuto)},
+			unit: "By",
+			want: mocks.MockStatSenderMethod{Method: "Raw", S: "latency", S2: "0.25|h", F: 1.0},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(tt.want)
+
+			mp := NewMeterProvider(append(tt.opts, WithStatsdClient(rs))...)
+			hist, err := mp.Meter("").Float64Histogram("latency", metric.WithUnit(tt.unit))
+			require.NoError(t, err)
+
+			hist.Record(context.Background(), 0.25)
+
+			rs.CHECK(t)
+		})
+	}
+}
+
+func TestProviderAggregation(t *testing.T) {
+	trigger := triggerTicker(t)
+
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 6, F: 1.0, Tags: []statsd.Tag{{"x", "a"}}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 4, F: 1.0, Tags: []statsd.Tag{{"x", "b"}}},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "temp", I: 21, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "GaugeDelta", S: "queue", I: -1, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Timing", S: "latency", I: 7, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Timing", S: "latency", I: 9, F: 1.0},
+		// Sent on Stop.
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 1, F: 1.0, Tags: []statsd.Tag{{"x", "a"}}},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithAggregation(true))
+	m := mp.Meter("")
+
+	hits, err := m.Int64Counter("hits")
+	require.NoError(t, err)
+	temp, err := m.Float64Gauge("temp")
+	require.NoError(t, err)
+	queue, err := m.Int64UpDownCounter("queue")
+	require.NoError(t, err)
+	latency, err := m.Int64Histogram("latency")
+	require.NoError(t, err)
+
+	err = mp.Start(ctx)
+	require.NoError(t, err)
+
+	a := metric.WithAttributes(attribute.String("x", "a"))
+	b := metric.WithAttributes(attribute.String("x", "b"))
+	hits.Add(ctx, 1, a)
+	hits.Add(ctx, 5, a)
+	hits.Add(ctx, 4, b)
+	temp.Record(ctx, 20)
+	temp.Record(ctx, 21)
+	queue.Add(ctx, 2)
+	queue.Add(ctx, -3)
+	latency.Record(ctx, 7)
+	latency.Record(ctx, 9)
+
+	require.Empty(t, rs.Output)
+	trigger <- time.Now()
+	// Wait for the first collection to complete.
+	trigger <- time.Now()
+
+	hits.Add(ctx, 1, a)
+
+	err = mp.Stop(ctx)
+	require.NoError(t, err)
+
+	rs.CHECK(t)
+}
+
+func TestProviderAggregationLookups(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "g", I: 4, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "c", I: 5, F: 1.0},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithAggregation(true))
+
+	// Instruments looked up on each measurement share their series.
+	for i := 0; i < 5; i++ {
+		g, err := mp.Meter("").Float64Gauge("g")
+		require.NoError(t, err)
+		g.Record(ctx, float64(i))
+		c, err := mp.Meter("").Int64Counter("c")
+		require.NoError(t, err)
+		c.Add(ctx, 1)
+	}
+	require.NoError(t, mp.ForceFlush(ctx))
+
+	rs.CHECK(t)
+}
+
+func TestProviderAggregationSamples(t *testing.T) {
+	ctx := context.Background()
+	fixedRand(t, 0.5)
+
+	rs := mocks.NewMockStatSender()
+	mp := NewMeterProvider(WithStatsdClient(rs), WithAggregation(true))
+	latency, err := mp.Meter("").Int64Histogram("latency")
+	require.NoError(t, err)
+
+	for i := 0; i < 4*maxAggregatedSamples; i++ {
+		latency.Record(ctx, int64(i))
+	}
+	require.NoError(t, mp.ForceFlush(ctx))
+
+	// The kept samples are sent with a rate scaling them back up to the
+	// count of records.
+	require.Len(t, rs.Output, maxAggregatedSamples)
+	for _, out := range rs.Output {
+		assert.Equal(t, "Timing", out.Method)
+		assert.Equal(t, float32(0.25), out.F)
+	}
+}
+
+func TestProviderNamespacing(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		want mocks.MockStatSenderMethod
+	}{
+		{
+			name: "Default",
+			want: mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 1, F: 1.0},
+		},
+		{
+			name: "Prefix",
+			opts: []Option{WithPrefix("svc.")},
+			want: mocks.MockStatSenderMethod{Method: "Inc", S: "svc.requests", I: 1, F: 1.0},
+		},
+		{
+			name: "ScopePrefix",
+			opts: []Option{WithPrefix("svc"), WithScopeMode(ScopeModePrefix)},
+			want: mocks.MockStatSenderMethod{Method: "Inc", S: "svc.github.com/x/http.requests", I: 1, F: 1.0},
+		},
+		{
+			name: "ScopePrefixVersion",
+			opts: []Option{WithScopeMode(ScopeModePrefix), WithScopeVersion(true)},
+			want: mocks.MockStatSenderMethod{Method: "Inc", S: "github.com/x/http.v1_2_0.requests", I: 1, F: 1.0},
+		},
+		{
+			name: "ScopeTags",
+			opts: []Option{WithScopeMode(ScopeModeTags), WithScopeVersion(true)},
+			want: mocks.MockStatSenderMethod{
+				Method: "Inc", S: "requests", I: 1, F: 1.0,
+				Tags: []statsd.Tag{{"otel.scope.name", "github.com/x/http"}, {"otel.scope.version", "v1.2.0"}},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(tt.want, tt.want)
+
+			mp := NewMeterProvider(append(tt.opts, WithStatsdClient(rs))...)
+			m := mp.Meter("github.com/x/http", metric.WithInstrumentationVersion("v1.2.0"))
+
+			ctr, err := m.Int64Counter("requests")
+			require.NoError(t, err)
+			ctr.Add(ctx, 1)
+
+			cback := func(_ context.Context, o metric.Int64Observer) error {
+				o.Observe(1)
+				return nil
+			}
+			_, err = m.Int64ObservableCounter("requests", metric.WithInt64Callback(cback))
+			require.NoError(t, err)
+			require.NoError(t, mp.produce(ctx))
+
+			rs.CHECK(t)
+		})
+	}
+}
+
+func TestProviderResourceTags(t *testing.T) {
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "api"),
+		attribute.String("host.name", "h1"),
+		attribute.String("telemetry.sdk.name", "opentelemetry"),
+	)
+
+	testCases := []struct {
+		name string
+		opts []Option
+		want []statsd.Tag
+	}{
+		{
+			name: "All",
+			want: []statsd.Tag{{"service.name", "api"}, {"host.name", "h1"}, {"telemetry.sdk.name", "opentelemetry"}},
+		},
+		{
+			name: "Disabled",
+			opts: []Option{WithResourceTags(false)},
+		},
+		{
+			name: "Allowlist",
+			opts: []Option{WithResourceTagAllowlist("service.name", "host.name")},
+			want: []statsd.Tag{{"service.name", "api"}, {"host.name", "h1"}},
+		},
+		{
+			name: "AllowlistAndDenylist",
+			opts: []Option{
+				WithResourceTagAllowlist("service.name", "host.name"),
+				WithResourceTagDenylist("host.name"),
+			},
+			want: []statsd.Tag{{"service.name", "api"}},
+		},
+		{
+			name: "Rename",
+			opts: []Option{
+				WithResourceTagDenylist("telemetry.sdk.name"),
+				WithResourceTagRename("service.name", "service"),
+			},
+			want: []statsd.Tag{{"service", "api"}, {"host.name", "h1"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mocks.NewMockStatSender()
+
+			opts := append([]Option{WithStatsdClient(rs), WithResource(res)}, tt.opts...)
+			mp := NewMeterProvider(opts...)
+			ctr, err := mp.Meter("").Int64Counter("hits")
+			require.NoError(t, err)
+			ctr.Add(context.Background(), 1)
+
+			require.Len(t, rs.Output, 1)
+			assert.ElementsMatch(t, tt.want, rs.Output[0].Tags)
+		})
+	}
+}
+
+func TestProviderCardinalityLimit(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	mp := NewMeterProvider(
+		WithStatsdClient(rs),
+		WithResourceTags(false),
+		WithCardinalityLimit(3),
+		WithView(NewView(sdkmetric.Instrument{Name: "unlimited"}, Stream{CardinalityLimit: -1})),
+	)
+
+	ctx := context.Background()
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+	for _, v := range []string{"a", "b", "c", "a", "d"} {
+		ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("k", v)))
+	}
+
+	unlimited, err := mp.Meter("").Int64Counter("unlimited")
+	require.NoError(t, err)
+	for _, v := range []string{"a", "b", "c"} {
+		unlimited.Add(ctx, 1, metric.WithAttributes(attribute.String("k", v)))
+	}
+
+	inc := func(name string, tag statsd.Tag) mocks.MockStatSenderMethod {
+		return mocks.MockStatSenderMethod{Method: "Inc", S: name, I: 1, F: 1.0, Tags: []statsd.Tag{tag}}
+	}
+	overflow := statsd.Tag{"otel.metric.overflow", "true"}
+	rs.EXPECT(
+		inc("hits", statsd.Tag{"k", "a"}),
+		inc("hits", statsd.Tag{"k", "b"}),
+		inc("hits", overflow),
+		inc("hits", statsd.Tag{"k", "a"}),
+		inc("hits", overflow),
+		inc("unlimited", statsd.Tag{"k", "a"}),
+		inc("unlimited", statsd.Tag{"k", "b"}),
+		inc("unlimited", statsd.Tag{"k", "c"}),
+	)
+	rs.CHECK(t)
+	assert.Equal(t, Stats{Overflows: 2}, mp.Stats())
+}
+
+func TestProviderCardinalityLimitLookups(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	mp := NewMeterProvider(WithStatsdClient(rs), WithResourceTags(false), WithCardinalityLimit(2))
+
+	// Instruments looked up on each request share the limit.
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		ctr, err := mp.Meter("").Int64Counter("c")
+		require.NoError(t, err)
+		ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("id", id)))
+	}
+
+	inc := func(tag statsd.Tag) mocks.MockStatSenderMethod {
+		return mocks.MockStatSenderMethod{Method: "Inc", S: "c", I: 1, F: 1.0, Tags: []statsd.Tag{tag}}
+	}
+	overflow := statsd.Tag{"otel.metric.overflow", "true"}
+	rs.EXPECT(inc(statsd.Tag{"id", "1"}), inc(overflow), inc(overflow), inc(overflow), inc(overflow))
+	rs.CHECK(t)
+	assert.Equal(t, Stats{Overflows: 4}, mp.Stats())
+
+	// The limit applies per collection interval.
+	require.NoError(t, mp.ForceFlush(ctx))
+	ctr, err := mp.Meter("").Int64Counter("c")
+	require.NoError(t, err)
+	ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("id", "6")))
+	ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("id", "7")))
+	require.Len(t, rs.Output, 7)
+	assert.Equal(t, []statsd.Tag{{"id", "6"}}, rs.Output[5].Tags)
+	assert.Equal(t, []statsd.Tag{overflow}, rs.Output[6].Tags)
+}
+
+func TestProviderStopWithoutStart(t *testing.T) {
+	mp := NewMeterProvider(WithStatsdClient(mocks.NewMockStatSender()))
+	require.NoError(t, mp.Stop(context.Background()))
+}
+
+func TestProviderForceFlush(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 1, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "load", I: 4, F: 1.0},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithResourceTags(false), WithWorkers(2))
+	require.NoError(t, mp.Start(ctx))
+
+	m := mp.Meter("")
+	ctr, err := m.Int64Counter("hits")
+	require.NoError(t, err)
+	_, err = m.Int64ObservableGauge("load", metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+		o.Observe(4)
+		return nil
+	}))
+	require.NoError(t, err)
+
+	ctr.Add(ctx, 1)
+	require.NoError(t, mp.ForceFlush(ctx))
+	rs.CHECK(t)
+
+	// Shutdown calls the callbacks a last time, then instruments do nothing.
+	require.NoError(t, mp.Shutdown(ctx))
+	require.Len(t, rs.Output, 3)
+	ctr.Add(ctx, 1)
+	require.NoError(t, mp.ForceFlush(ctx))
+	assert.Len(t, rs.Output, 3)
+}
+
+// blockingStatSender blocks sending counters until unblock is closed.
+type blockingStatSender struct {
+	*mocks.MockStatSender
+	unblock chan struct{}
+}
+
+func (s blockingStatSender) Inc(stat string, value int64, rate float32, tags ...statsd.Tag) error {
+	<-s.unblock
+	return s.MockStatSender.Inc(stat, value, rate, tags...)
+}
+
+func TestProviderShutdownTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := newTCPServer(t, ln)
+	goroutines := runtime.NumGoroutine()
+
+	rs := blockingStatSender{mocks.NewMockStatSender(), make(chan struct{})}
+	mp := NewMeterProvider(
+		WithStatsdClient(rs),
+		WithWorkers(1),
+		WithResourceTags(false),
+		WithDestination(Destination{Endpoint: "tcp://" + ln.Addr().String()}),
+	)
+	require.NoError(t, mp.Start(context.Background()))
+
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+	ctr.Add(context.Background(), 1)
+	ctr.Add(context.Background(), 1)
+	assert.Equal(t, []string{"hits:1|c", "hits:1|c"}, srv.read(t, 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, mp.ForceFlush(ctx), context.DeadlineExceeded)
+	require.ErrorIs(t, mp.Shutdown(ctx), context.DeadlineExceeded)
+	require.NoError(t, mp.Shutdown(context.Background()))
+
+	// The workers are stopped, the stat queued behind the blocked one is
+	// dropped, and the transport is closed.
+	for _, d := range mp.destinations {
+		d.workers.mu.Lock()
+		assert.Equal(t, workerStopped, d.workers.state)
+		d.workers.mu.Unlock()
+	}
+	assert.Equal(t, int64(1), mp.Stats().Dropped)
+	_, err = mp.destinations[1].transport.Send([]byte("hits:1|c"))
+	require.ErrorIs(t, err, errTransportClosed)
+
+	// The worker returns once its stat is sent, and the connection is
+	// closed.
+	close(rs.unblock)
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestProviderRestart(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	mp := NewMeterProvider(WithStatsdClient(rs), WithWorkers(2))
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+
+	require.NoError(t, mp.Start(ctx))
+	require.ErrorIs(t, mp.Start(ctx), errStarted)
+	ctr.Add(ctx, 1)
+	require.NoError(t, mp.Stop(ctx))
+	require.Len(t, rs.Output, 1)
+
+	// Stats are rejected by the stopped workers until Start.
+	ctr.Add(ctx, 1)
+	require.Len(t, rs.Output, 1)
+
+	require.NoError(t, mp.Start(ctx))
+	ctr.Add(ctx, 1)
+	require.NoError(t, mp.Shutdown(ctx))
+	require.Len(t, rs.Output, 2)
+
+	require.ErrorIs(t, mp.Start(ctx), errShutdown)
+	require.NoError(t, mp.Stop(ctx))
+}
+
+func TestProviderBufferedClientNotFlushed(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	client, err := statsd.NewClientWithConfig(&statsd.ClientConfig{
+		Address:       pc.LocalAddr().String(),
+		UseBuffered:   true,
+		FlushInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	mp := NewMeterProvider(WithStatsdClient(client), WithResourceTags(false))
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+	ctr.Add(context.Background(), 1)
+
+	// The buffered sender has no Flush method, so ForceFlush leaves the
+	// stat in its buffer.
+	require.NoError(t, mp.ForceFlush(context.Background()))
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, _, err = pc.ReadFrom(buf)
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+
+	// Only closing the client sends it.
+	require.NoError(t, client.Close())
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hits:1|c", string(buf[:n]))
+}
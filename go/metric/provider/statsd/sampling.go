@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"go.opentelemetry.io/otel"
+)
+
+type sampleRateKey struct{}
+
+// ContextWithSampleRate returns a copy of ctx with the sample rate to use for
+// measurements made with it, overriding the rate of the instrument. A rate
+// outside (0, 1] is reported with otel.Handle and ctx is returned as is.
+func ContextWithSampleRate(ctx context.Context, rate float32) context.Context {
+	if !validSampleRate(rate) {
+		return ctx
+	}
+	return context.WithValue(ctx, sampleRateKey{}, rate)
+}
+
+func sampleRateFromContext(ctx context.Context) (float32, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	rate, ok := ctx.Value(sampleRateKey{}).(float32)
+	return rate, ok
+}
+
+// validSampleRate returns true if rate is in (0, 1], and reports it with
+// otel.Handle otherwise.
+func validSampleRate(rate float32) bool {
+	if rate > 0 && rate <= 1 {
+		return true
+	}
+	otel.Handle(fmt.Errorf("invalid sample rate %v ignored: want a rate in (0, 1]", rate))
+	return false
+}
+
+// randFloat32 allows testing override.
+var randFloat32 = rand.Float32
+
+// sampled returns true if a measurement sampled at rate must be sent.
+func sampled(rate float32) bool {
+	if rate >= 1 {
+		return true
+	}
+	return randFloat32() < rate
+}
+
+// sampleable returns true if the stats of type t can be sampled. StatsD
+// servers ignore the rate of gauges, so sampling them would skew their value.
+func (t statType) sampleable() bool {
+	return t != statGauge && t != statGaugeDelta
+}
+
+// subStatter is implemented by the statsd clients creating copies of
+// themselves, such as *statsd.Client.
+type subStatter interface {
+	NewSubStatter(string) statsd.SubStatter
+}
+
+// presampledClient returns a client sending the stats to the same sender as
+// client, without sampling them again. client itself is left unchanged, as it
+// may be shared with other code.
+func presampledClient(client statsd.StatSender) statsd.StatSender {
+	sub, ok := client.(subStatter)
+	if !ok {
+		return client
+	}
+	ret := sub.NewSubStatter("")
+	ret.SetSamplerFunc(presampled)
+	return ret
+}
+
+// presampled is a statsd.SamplerFunc sending every measurement, as they are
+// sampled by the MeterProvider.
+func presampled(float32) bool {
+	return true
+}
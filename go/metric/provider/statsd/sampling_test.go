@@ -0,0 +1,151 @@
+package statsd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedRand(t *testing.T, v float32) {
+	t.Helper()
+
+	orig := randFloat32
+	t.Cleanup(func() { randFloat32 = orig })
+	randFloat32 = func() float32 { return v }
+}
+
+func TestSampleRate(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		ctx  context.Context
+		rand float32
+		want []mocks.MockStatSenderMethod
+	}{
+		{
+			name: "Default",
+			ctx:  context.Background(),
+			rand: 0.99,
+			want: []mocks.MockStatSenderMethod{{Method: "Inc", S: "hits", I: 1, F: 1.0}},
+		},
+		{
+			name: "Global",
+			opts: []Option{WithSampleRate(0.25)},
+			ctx:  context.Background(),
+			rand: 0.1,
+			want: []mocks.MockStatSenderMethod{{Method: "Inc", S: "hits", I: 1, F: 0.25}},
+		},
+		{
+			name: "GlobalDropped",
+			opts: []Option{WithSampleRate(0.25)},
+			ctx:  context.Background(),
+			rand: 0.3,
+		},
+		{
+			name: "Instrument",
+			opts: []Option{WithSampleRate(0.25), WithInstrumentSampleRate("hits", 0.5)},
+			ctx:  context.Background(),
+			rand: 0.3,
+			want: []mocks.MockStatSenderMethod{{Method: "Inc", S: "hits", I: 1, F: 0.5}},
+		},
+		{
+			name: "Context",
+			opts: []Option{WithInstrumentSampleRate("hits", 0.5)},
+			ctx:  ContextWithSampleRate(context.Background(), 0.1),
+			rand: 0.05,
+			want: []mocks.MockStatSenderMethod{{Method: "Inc", S: "hits", I: 1, F: 0.1}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			fixedRand(t, tt.rand)
+
+			rs := mocks.NewMockStatSender()
+			rs.EXPECT(tt.want...)
+
+			mp := NewMeterProvider(append(tt.opts, WithStatsdClient(rs))...)
+			ctr, err := mp.Meter("").Int64Counter("hits")
+			require.NoError(t, err)
+
+			ctr.Add(tt.ctx, 1)
+
+			rs.CHECK(t)
+		})
+	}
+}
+
+func TestSampleRateSkipsGauges(t *testing.T) {
+	ctx := context.Background()
+	fixedRand(t, 0.99)
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "temp", I: 21, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "GaugeDelta", S: "queue", I: 2, F: 1.0},
+	)
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithSampleRate(0.25))
+	m := mp.Meter("")
+	temp, err := m.Int64Gauge("temp")
+	require.NoError(t, err)
+	queue, err := m.Int64UpDownCounter("queue")
+	require.NoError(t, err)
+	hits, err := m.Int64Counter("hits")
+	require.NoError(t, err)
+
+	temp.Record(ctx, 21)
+	queue.Add(ctx, 2)
+	hits.Add(ctx, 1)
+
+	rs.CHECK(t)
+}
+
+func TestSampleRateKeepsClientSampler(t *testing.T) {
+	ctx := context.Background()
+	fixedRand(t, 0)
+
+	rs := &recordingSender{}
+	client, err := statsd.NewClientWithSender(rs, "", statsd.SuffixOctothorpe)
+	require.NoError(t, err)
+	client.(statsd.SubStatter).SetSamplerFunc(func(float32) bool { return false })
+
+	mp := NewMeterProvider(WithStatsdClient(client), WithResourceTags(false), WithSampleRate(0.5))
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+
+	// The measurements sampled by the MeterProvider are not sampled again,
+	// while the sampler of the client is left unchanged.
+	ctr.Add(ctx, 1)
+	require.NoError(t, client.Inc("other", 1, 0.5))
+	assert.Equal(t, []string{"hits:1|c|@0.500000"}, rs.sent())
+}
+
+func TestInvalidSampleRate(t *testing.T) {
+	errs := handledErrors(t)
+	fixedRand(t, 0.99)
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 1, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "hits", I: 2, F: 1.0},
+	)
+
+	mp := NewMeterProvider(
+		WithStatsdClient(rs),
+		WithSampleRate(0),
+		WithInstrumentSampleRate("hits", 1.5),
+	)
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+
+	ctr.Add(context.Background(), 1)
+	ctr.Add(ContextWithSampleRate(context.Background(), -1), 2)
+
+	rs.CHECK(t)
+	assert.Len(t, *errs, 3)
+}
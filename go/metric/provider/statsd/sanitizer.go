@@ -0,0 +1,140 @@
+package statsd
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sanitizer makes metric names and tags safe to write in the StatsD line
+// protocol.
+type Sanitizer interface {
+	// Name returns the sanitized metric name.
+	Name(string) string
+	// TagKey returns the sanitized tag key.
+	TagKey(string) string
+	// TagValue returns the sanitized tag value.
+	TagValue(string) string
+}
+
+// defaultMaxLength is the maximum length in bytes of the names, tag keys and
+// tag values produced by the provided sanitizers.
+const defaultMaxLength = 200
+
+// runeSanitizer replaces the runes that are not valid in each part of a
+// metric with an underscore, collapses consecutive dots in names, and
+// truncates each part to maxLength bytes.
+type runeSanitizer struct {
+	validName  func(rune) bool
+	validKey   func(rune) bool
+	validValue func(rune) bool
+	maxLength  int
+}
+
+// NewDefaultSanitizer returns the Sanitizer used by default. It replaces the
+// characters that are part of the StatsD line protocol or of one of its tag
+// formats (:|@#,=;), whitespace and control characters.
+func NewDefaultSanitizer() Sanitizer {
+	return runeSanitizer{
+		validName:  validDefault,
+		validKey:   validDefault,
+		validValue: validDefault,
+		maxLength:  defaultMaxLength,
+	}
+}
+
+// NewGraphiteSanitizer returns a Sanitizer following the Graphite rules:
+// names and tag keys only contain ASCII letters, digits, and _-. characters,
+// and tag values do not contain ; or ~.
+func NewGraphiteSanitizer() Sanitizer {
+	return runeSanitizer{
+		validName: validGraphite,
+		validKey:  validGraphite,
+		validValue: func(r rune) bool {
+			return r != '~' && validDefault(r)
+		},
+		maxLength: defaultMaxLength,
+	}
+}
+
+// NewDatadogSanitizer returns a Sanitizer following the Datadog rules: names
+// only contain ASCII letters, digits, underscores and dots, tag keys also
+// contain -/ characters, and tag values also contain colons.
+func NewDatadogSanitizer() Sanitizer {
+	return runeSanitizer{
+		validName: func(r rune) bool {
+			return isASCIIAlnum(r) || r == '_' || r == '.'
+		},
+		validKey: func(r rune) bool {
+			return isASCIIAlnum(r) || strings.ContainsRune("_-./", r)
+		},
+		validValue: func(r rune) bool {
+			return isASCIIAlnum(r) || strings.ContainsRune("_-./:", r)
+		},
+		maxLength: defaultMaxLength,
+	}
+}
+
+func (s runeSanitizer) Name(name string) string {
+	name = s.replace(name, s.validName)
+	if strings.Contains(name, "..") {
+		var b strings.Builder
+		for i := 0; i < len(name); i++ {
+			if name[i] == '.' && i > 0 && name[i-1] == '.' {
+				continue
+			}
+			b.WriteByte(name[i])
+		}
+		name = b.String()
+	}
+	return truncate(strings.Trim(name, "."), s.maxLength)
+}
+
+func (s runeSanitizer) TagKey(key string) string {
+	return truncate(s.replace(key, s.validKey), s.maxLength)
+}
+
+func (s runeSanitizer) TagValue(value string) string {
+	return truncate(s.replace(value, s.validValue), s.maxLength)
+}
+
+// replace replaces the runes of str that are not valid with underscores. str
+// is returned as is if all its runes are valid.
+func (s runeSanitizer) replace(str string, valid func(rune) bool) string {
+	if strings.IndexFunc(str, func(r rune) bool { return !valid(r) }) < 0 {
+		return str
+	}
+	return strings.Map(func(r rune) rune {
+		if valid(r) {
+			return r
+		}
+		return '_'
+	}, str)
+}
+
+// truncate returns the longest prefix of str of at most max bytes that does
+// not split a rune.
+func truncate(str string, max int) string {
+	if max <= 0 || len(str) <= max {
+		return str
+	}
+	for max > 0 && !utf8.RuneStart(str[max]) {
+		max--
+	}
+	return str[:max]
+}
+
+func validDefault(r rune) bool {
+	return r != utf8.RuneError &&
+		!unicode.IsSpace(r) &&
+		!unicode.IsControl(r) &&
+		!strings.ContainsRune(":|@#,=;", r)
+}
+
+func validGraphite(r rune) bool {
+	return isASCIIAlnum(r) || r == '_' || r == '-' || r == '.'
+}
+
+func isASCIIAlnum(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
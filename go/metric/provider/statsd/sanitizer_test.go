@@ -0,0 +1,99 @@
+package statsd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestSanitizers(t *testing.T) {
+	testCases := []struct {
+		name      string
+		sanitizer Sanitizer
+		in        string
+		wantName  string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name:      "DefaultValid",
+			sanitizer: NewDefaultSanitizer(),
+			in:        "http.server/duration",
+			wantName:  "http.server/duration",
+			wantKey:   "http.server/duration",
+			wantValue: "http.server/duration",
+		},
+		{
+			name:      "DefaultInvalid",
+			sanitizer: NewDefaultSanitizer(),
+			in:        ".a:b|c@d#e,f=g;h i\nj..k.",
+			wantName:  "a_b_c_d_e_f_g_h_i_j.k",
+			wantKey:   ".a_b_c_d_e_f_g_h_i_j..k.",
+			wantValue: ".a_b_c_d_e_f_g_h_i_j..k.",
+		},
+		{
+			name:      "Graphite",
+			sanitizer: NewGraphiteSanitizer(),
+			in:        "a/b~c:d",
+			wantName:  "a_b_c_d",
+			wantKey:   "a_b_c_d",
+			wantValue: "a/b_c_d",
+		},
+		{
+			name:      "Datadog",
+			sanitizer: NewDatadogSanitizer(),
+			in:        "a/b-c:d é",
+			wantName:  "a_b_c_d__",
+			wantKey:   "a/b-c_d__",
+			wantValue: "a/b-c:d__",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantName, tt.sanitizer.Name(tt.in))
+			assert.Equal(t, tt.wantKey, tt.sanitizer.TagKey(tt.in))
+			assert.Equal(t, tt.wantValue, tt.sanitizer.TagValue(tt.in))
+		})
+	}
+}
+
+func TestSanitizerTruncates(t *testing.T) {
+	s := NewDefaultSanitizer()
+
+	assert.Len(t, s.Name(strings.Repeat("a", 300)), defaultMaxLength)
+	// Runes are not split.
+	assert.Equal(t, strings.Repeat("a", 199), s.TagValue(strings.Repeat("a", 199)+"éé"))
+}
+
+func TestProviderSanitizes(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "svc.req_count", I: 1, F: 1.0, Tags: []statsd.Tag{{"user_id", "a_b"}}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "svc..req|count", I: 1, F: 1.0, Tags: []statsd.Tag{{"user|id", "a|b"}}},
+	)
+
+	attrs := metric.WithAttributes(attribute.String("user|id", "a|b"))
+
+	mp := NewMeterProvider(WithStatsdClient(rs), WithResource(resource.Empty()), WithPrefix("svc."))
+	ctr, err := mp.Meter("").Int64Counter("req|count")
+	require.NoError(t, err)
+	ctr.Add(ctx, 1, attrs)
+
+	mp = NewMeterProvider(WithStatsdClient(rs), WithResource(resource.Empty()), WithPrefix("svc."), WithSanitizer(nil))
+	ctr, err = mp.Meter("").Int64Counter(".req|count")
+	require.NoError(t, err)
+	ctr.Add(ctx, 1, attrs)
+
+	rs.CHECK(t)
+}
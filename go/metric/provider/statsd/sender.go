@@ -0,0 +1,126 @@
+package statsd
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+)
+
+// StatSender is the sender used by the MeterProvider. It extends
+// statsd.StatSender with methods that send float64 values without truncating
+// them to int64.
+type StatSender interface {
+	statsd.StatSender
+	IncFloat(string, float64, float32, ...statsd.Tag) error
+	GaugeFloat(string, float64, float32, ...statsd.Tag) error
+	GaugeFloatDelta(string, float64, float32, ...statsd.Tag) error
+	TimingFloat(string, float64, float32, ...statsd.Tag) error
+	HistogramFloat(string, float64, float32, ...statsd.Tag) error
+	DistributionFloat(string, float64, float32, ...statsd.Tag) error
+}
+
+// statType is the StatsD type a value is sent as.
+type statType int
+
+const (
+	statCount        statType = iota // |c
+	statGauge                        // |g
+	statGaugeDelta                   // +N|g or -N|g
+	statTiming                       // |ms
+	statHistogram                    // |h
+	statDistribution                 // |d
+)
+
+// sendStat sends val to s as a StatsD metric of type t.
+func sendStat(s StatSender, t statType, stat string, val float64, rate float32, tags ...statsd.Tag) error {
+	switch t {
+	case statGauge:
+		return s.GaugeFloat(stat, val, rate, tags...)
+	case statGaugeDelta:
+		return s.GaugeFloatDelta(stat, val, rate, tags...)
+	case statTiming:
+		return s.TimingFloat(stat, val, rate, tags...)
+	case statHistogram:
+		return s.HistogramFloat(stat, val, rate, tags...)
+	case statDistribution:
+		return s.DistributionFloat(stat, val, rate, tags...)
+	default:
+		return s.IncFloat(stat, val, rate, tags...)
+	}
+}
+
+// NewStatSender returns s as a StatSender. If s does not implement StatSender,
+// float values that are not integral are written with s.Raw, and integral ones
+// with the matching int64 method.
+func NewStatSender(s statsd.StatSender) StatSender {
+	if ss, ok := s.(StatSender); ok {
+		return ss
+	}
+	return rawStatSender{s}
+}
+
+// rawStatSender adapts a statsd.StatSender to StatSender.
+type rawStatSender struct {
+	statsd.StatSender
+}
+
+func (s rawStatSender) IncFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	if i, ok := integral(value); ok {
+		return s.Inc(stat, i, rate, tags...)
+	}
+	return s.Raw(stat, formatFloat(value)+"|c", rate, tags...)
+}
+
+func (s rawStatSender) GaugeFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	if i, ok := integral(value); ok {
+		return s.Gauge(stat, i, rate, tags...)
+	}
+	if es, ok := s.StatSender.(statsd.ExtendedStatSender); ok {
+		return es.GaugeFloat(stat, value, rate, tags...)
+	}
+	return s.Raw(stat, formatFloat(value)+"|g", rate, tags...)
+}
+
+func (s rawStatSender) GaugeFloatDelta(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	if i, ok := integral(value); ok {
+		return s.GaugeDelta(stat, i, rate, tags...)
+	}
+	if es, ok := s.StatSender.(statsd.ExtendedStatSender); ok {
+		return es.GaugeFloatDelta(stat, value, rate, tags...)
+	}
+	if value >= 0 {
+		return s.Raw(stat, "+"+formatFloat(value)+"|g", rate, tags...)
+	}
+	return s.Raw(stat, formatFloat(value)+"|g", rate, tags...)
+}
+
+func (s rawStatSender) TimingFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	if i, ok := integral(value); ok {
+		return s.Timing(stat, i, rate, tags...)
+	}
+	return s.Raw(stat, formatFloat(value)+"|ms", rate, tags...)
+}
+
+// HistogramFloat sends a histogram (|h), which not all servers support.
+func (s rawStatSender) HistogramFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.Raw(stat, formatFloat(value)+"|h", rate, tags...)
+}
+
+// DistributionFloat sends a DogStatsD distribution (|d).
+func (s rawStatSender) DistributionFloat(stat string, value float64, rate float32, tags ...statsd.Tag) error {
+	return s.Raw(stat, formatFloat(value)+"|d", rate, tags...)
+}
+
+// integral returns v as an int64 if it has no fractional part and fits in an
+// int64.
+func integral(v float64) (int64, bool) {
+	if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(v), true
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,42 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawStatSender(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "c", I: 3, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "c", S2: "0.25|c", F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "g", I: -4, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "g", S2: "1.5|g", F: 1.0},
+		mocks.MockStatSenderMethod{Method: "GaugeDelta", S: "gd", I: 2, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "gd", S2: "+0.5|g", F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "gd", S2: "-0.5|g", F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Timing", S: "t", I: 7, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "t", S2: "0.75|ms", F: 0.5, Tags: []statsd.Tag{{"x", "y"}}},
+	)
+
+	s := NewStatSender(rs)
+	require.NoError(t, s.IncFloat("c", 3, 1.0))
+	require.NoError(t, s.IncFloat("c", 0.25, 1.0))
+	require.NoError(t, s.GaugeFloat("g", -4, 1.0))
+	require.NoError(t, s.GaugeFloat("g", 1.5, 1.0))
+	require.NoError(t, s.GaugeFloatDelta("gd", 2, 1.0))
+	require.NoError(t, s.GaugeFloatDelta("gd", 0.5, 1.0))
+	require.NoError(t, s.GaugeFloatDelta("gd", -0.5, 1.0))
+	require.NoError(t, s.TimingFloat("t", 7, 1.0))
+	require.NoError(t, s.TimingFloat("t", 0.75, 0.5, statsd.Tag{"x", "y"}))
+
+	rs.CHECK(t)
+}
+
+func TestNewStatSenderKeepsStatSender(t *testing.T) {
+	s := NewStatSender(mocks.NewMockStatSender())
+	require.Equal(t, s, NewStatSender(s))
+}
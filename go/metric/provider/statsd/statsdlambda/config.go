@@ -0,0 +1,46 @@
+package statsdlambda
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type config struct {
+	// Maximum duration of the flush at the end of an invocation
+	FlushTimeout time.Duration
+
+	// Attributes of the invocation metrics
+	Attributes []attribute.KeyValue
+}
+
+// Option applies a configuration option value to WrapHandler.
+type Option interface {
+	apply(config) config
+}
+
+// WithFlushTimeout sets the maximum duration of the flush at the end of each
+// invocation. Defaults to 1 second.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return flushTimeoutOption{timeout}
+}
+
+type flushTimeoutOption struct{ timeout time.Duration }
+
+func (o flushTimeoutOption) apply(cfg config) config {
+	cfg.FlushTimeout = o.timeout
+	return cfg
+}
+
+// WithAttributes adds attributes to the invocation metrics, like the
+// function name.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return attributesOption{attrs}
+}
+
+type attributesOption struct{ attrs []attribute.KeyValue }
+
+func (o attributesOption) apply(cfg config) config {
+	cfg.Attributes = append(cfg.Attributes[:len(cfg.Attributes):len(cfg.Attributes)], o.attrs...)
+	return cfg
+}
@@ -0,0 +1,143 @@
+// Package statsdlambda wraps AWS Lambda handlers to send the metrics of a
+// statsd.MeterProvider at the end of each invocation.
+//
+// Lambda freezes the execution environment once the handler returns, so
+// stats still queued by the workers or batched by the transport may be sent
+// minutes later, or never. The wrapped handler calls ForceFlush before
+// returning, which also calls the observable callbacks, so the periodic
+// collection started by MeterProvider.Start is not needed.
+//
+//	mp := statsd.NewMeterProvider(statsd.WithBatching(statsd.MTUUDP))
+//	lambda.Start(statsdlambda.WrapHandler(mp, handler))
+//
+// ForceFlush cannot flush a buffered client of
+// github.com/cactus/go-statsd-client (UseBuffered), so a client set with
+// statsd.WithStatsdClient must not be buffered.
+package statsdlambda
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd"
+)
+
+const (
+	// ScopeName is the instrumentation scope of the invocation metrics.
+	ScopeName = "github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/statsdlambda"
+
+	defaultFlushTimeout = time.Second
+)
+
+// Metric names, from the FaaS semantic conventions.
+const (
+	coldStartsName     = "faas.coldstarts"
+	errorsName         = "faas.errors"
+	invokeDurationName = "faas.invoke_duration"
+)
+
+// now allows testing override.
+var now = time.Now
+
+// WrapHandler returns a handler calling handler, then sending the invocation
+// metrics and flushing mp before returning. It records:
+//   - faas.coldstarts, incremented on the first invocation,
+//   - faas.invoke_duration, the duration of handler in seconds, sent as a
+//     timing in milliseconds,
+//   - faas.errors, incremented when handler returns an error or panics.
+//
+// Errors creating the instruments and flushing mp are reported with
+// otel.Handle, they are not returned by the handler.
+func WrapHandler[TIn, TOut any](mp *statsd.MeterProvider, handler func(context.Context, TIn) (TOut, error), opts ...Option) func(context.Context, TIn) (TOut, error) {
+	inv := newInvocations(mp, opts...)
+	return func(ctx context.Context, in TIn) (out TOut, err error) {
+		start := inv.begin(ctx)
+		failed := true
+		defer func() { inv.end(ctx, start, failed) }()
+
+		out, err = handler(ctx, in)
+		failed = err != nil
+		return out, err
+	}
+}
+
+// invocations records the metrics of the invocations of a handler.
+type invocations struct {
+	provider     *statsd.MeterProvider
+	flushTimeout time.Duration
+	attrs        metric.MeasurementOption
+
+	coldStarts     metric.Int64Counter
+	errors         metric.Int64Counter
+	invokeDuration metric.Float64Histogram
+
+	// coldStart is true until the first invocation. Lambda runs one
+	// invocation at a time in an execution environment.
+	coldStart bool
+}
+
+func newInvocations(mp *statsd.MeterProvider, opts ...Option) *invocations {
+	c := config{
+		FlushTimeout: defaultFlushTimeout,
+	}
+	for _, opt := range opts {
+		c = opt.apply(c)
+	}
+
+	m := mp.Meter(ScopeName)
+	ret := &invocations{
+		provider:     mp,
+		flushTimeout: c.FlushTimeout,
+		attrs:        metric.WithAttributeSet(attribute.NewSet(c.Attributes...)),
+		coldStart:    true,
+	}
+
+	var err error
+	ret.coldStarts, err = m.Int64Counter(coldStartsName,
+		metric.WithDescription("Number of invocation cold starts"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	ret.errors, err = m.Int64Counter(errorsName,
+		metric.WithDescription("Number of invocation errors"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	ret.invokeDuration, err = m.Float64Histogram(invokeDurationName,
+		metric.WithDescription("Duration of the invocations"), metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return ret
+}
+
+// begin records the start of an invocation and returns its start time.
+func (i *invocations) begin(ctx context.Context) time.Time {
+	if i.coldStart {
+		i.coldStart = false
+		i.coldStarts.Add(ctx, 1, i.attrs)
+	}
+	return now()
+}
+
+// end records the end of an invocation started at start, then flushes the
+// MeterProvider.
+func (i *invocations) end(ctx context.Context, start time.Time, failed bool) {
+	i.invokeDuration.Record(ctx, now().Sub(start).Seconds(), i.attrs)
+	if failed {
+		i.errors.Add(ctx, 1, i.attrs)
+	}
+
+	// Flush even if the invocation was canceled, but not past the flush
+	// timeout.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), i.flushTimeout)
+	defer cancel()
+	if err := i.provider.ForceFlush(ctx); err != nil {
+		otel.Handle(err)
+	}
+}
@@ -0,0 +1,112 @@
+package statsdlambda
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	otelstatsd "github.com/SibrosTech/otel-statsd/go/metric/provider/statsd"
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+)
+
+// fixedDuration makes invocations last d.
+func fixedDuration(t *testing.T, d time.Duration) {
+	orig := now
+	t.Cleanup(func() { now = orig })
+	start := time.Unix(0, 0)
+	calls := 0
+	now = func() time.Time {
+		calls++
+		if calls%2 == 0 {
+			return start.Add(d)
+		}
+		return start
+	}
+}
+
+func TestWrapHandler(t *testing.T) {
+	fixedDuration(t, 5*time.Millisecond)
+
+	rs := mocks.NewMockStatSender()
+	mp := otelstatsd.NewMeterProvider(
+		otelstatsd.WithStatsdClient(rs),
+		otelstatsd.WithResourceTags(false),
+		otelstatsd.WithWorkers(1),
+	)
+
+	_, err := mp.Meter("app").Int64ObservableGauge("queue", metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+		o.Observe(3)
+		return nil
+	}))
+	require.NoError(t, err)
+
+	fn := WrapHandler(mp, func(_ context.Context, in string) (string, error) {
+		if in == "" {
+			return "", errors.New("empty")
+		}
+		return "hello " + in, nil
+	}, WithAttributes(attribute.String("faas.name", "greet")))
+
+	fnTag := statsd.Tag{"faas.name", "greet"}
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "faas.coldstarts", I: 1, F: 1.0, Tags: []statsd.Tag{fnTag}},
+		mocks.MockStatSenderMethod{Method: "Timing", S: "faas.invoke_duration", I: 5, F: 1.0, Tags: []statsd.Tag{fnTag}},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "queue", I: 3, F: 1.0},
+	)
+	out, err := fn(context.Background(), "world")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", out)
+	// The stats are sent before the handler returns.
+	rs.CHECK(t)
+
+	rs.Output = nil
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Timing", S: "faas.invoke_duration", I: 5, F: 1.0, Tags: []statsd.Tag{fnTag}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "faas.errors", I: 1, F: 1.0, Tags: []statsd.Tag{fnTag}},
+		mocks.MockStatSenderMethod{Method: "Gauge", S: "queue", I: 3, F: 1.0},
+	)
+	_, err = fn(context.Background(), "")
+	require.EqualError(t, err, "empty")
+	rs.CHECK(t)
+}
+
+func TestWrapHandlerPanic(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	mp := otelstatsd.NewMeterProvider(otelstatsd.WithStatsdClient(rs))
+
+	fn := WrapHandler(mp, func(context.Context, struct{}) (struct{}, error) {
+		panic("boom")
+	})
+	require.PanicsWithValue(t, "boom", func() {
+		_, _ = fn(context.Background(), struct{}{})
+	})
+
+	var methods []string
+	for _, out := range rs.Output {
+		methods = append(methods, out.S)
+	}
+	assert.Equal(t, []string{"faas.coldstarts", "faas.invoke_duration", "faas.errors"}, methods)
+}
+
+func TestWrapHandlerCanceled(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	mp := otelstatsd.NewMeterProvider(otelstatsd.WithStatsdClient(rs), otelstatsd.WithWorkers(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fn := WrapHandler(mp, func(ctx context.Context, _ int) (int, error) {
+		cancel()
+		return 0, ctx.Err()
+	})
+	_, err := fn(ctx, 0)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The stats are flushed even though the invocation was canceled.
+	assert.Len(t, rs.Output, 3)
+}
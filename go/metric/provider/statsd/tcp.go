@@ -0,0 +1,353 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default TCP transport settings.
+const (
+	defaultTCPMinBackoff = time.Millisecond * 100
+	defaultTCPMaxBackoff = time.Second * 30
+	defaultTCPTimeout    = time.Second
+	defaultTCPBufferSize = 64 * 1024
+	initialTCPBufferSize = 1024
+)
+
+var errTCPBufferFull = errors.New("tcp transport disconnected and buffer full: dropping stats")
+
+// ConnectionState is the state of the connection of a TCPTransport.
+type ConnectionState int
+
+const (
+	// ConnectionStateConnected is reported once connected.
+	ConnectionStateConnected ConnectionState = iota + 1
+	// ConnectionStateDisconnected is reported when a connection or a write
+	// fails, with the error.
+	ConnectionStateDisconnected
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionStateConnected:
+		return "connected"
+	case ConnectionStateDisconnected:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// TCPStats are the counters of a TCPTransport.
+type TCPStats struct {
+	// Sent is the number of successful writes.
+	Sent int64
+	// Dropped is the number of lines dropped because the buffer was full
+	// while disconnected, or because they were cut by a failed write.
+	Dropped int64
+	// Errors is the number of failed connections and writes.
+	Errors int64
+	// Reconnects is the number of connections after the first one.
+	Reconnects int64
+}
+
+type tcpConfig struct {
+	TLSConfig     *tls.Config
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+	Timeout       time.Duration
+	BufferSize    int
+	StateCallback func(ConnectionState, error)
+}
+
+// TCPOption applies a configuration option value to a TCPTransport.
+type TCPOption interface {
+	apply(tcpConfig) tcpConfig
+}
+
+// WithTCPTLSConfig enables TLS with cfg.
+func WithTCPTLSConfig(cfg *tls.Config) TCPOption {
+	return tcpTLSConfigOption{cfg}
+}
+
+type tcpTLSConfigOption struct{ cfg *tls.Config }
+
+func (o tcpTLSConfigOption) apply(cfg tcpConfig) tcpConfig {
+	cfg.TLSConfig = o.cfg
+	return cfg
+}
+
+// WithTCPBackoff sets the minimum and maximum time between connection
+// attempts. The time doubles after each failure. Defaults are 100ms and 30s.
+func WithTCPBackoff(min, max time.Duration) TCPOption {
+	return tcpBackoffOption{min, max}
+}
+
+type tcpBackoffOption struct{ min, max time.Duration }
+
+func (o tcpBackoffOption) apply(cfg tcpConfig) tcpConfig {
+	cfg.MinBackoff = o.min
+	cfg.MaxBackoff = o.max
+	return cfg
+}
+
+// WithTCPTimeout sets the timeout of connections and writes. Default is 1s.
+func WithTCPTimeout(timeout time.Duration) TCPOption {
+	return tcpTimeoutOption{timeout}
+}
+
+type tcpTimeoutOption struct{ timeout time.Duration }
+
+func (o tcpTimeoutOption) apply(cfg tcpConfig) tcpConfig {
+	cfg.Timeout = o.timeout
+	return cfg
+}
+
+// WithTCPBufferSize sets the maximum size in bytes of the lines buffered
+// while disconnected. Default is 64KiB.
+func WithTCPBufferSize(size int) TCPOption {
+	return tcpBufferSizeOption{size}
+}
+
+type tcpBufferSizeOption struct{ size int }
+
+func (o tcpBufferSizeOption) apply(cfg tcpConfig) tcpConfig {
+	cfg.BufferSize = o.size
+	return cfg
+}
+
+// WithTCPStateCallback sets a function called when the transport connects
+// or disconnects. It is called while sending, so it must not block nor use
+// the transport.
+func WithTCPStateCallback(f func(ConnectionState, error)) TCPOption {
+	return tcpStateCallbackOption{f}
+}
+
+type tcpStateCallbackOption struct {
+	f func(ConnectionState, error)
+}
+
+func (o tcpStateCallbackOption) apply(cfg tcpConfig) tcpConfig {
+	cfg.StateCallback = o.f
+	return cfg
+}
+
+// TCPTransport sends stats as lines terminated by newlines over TCP,
+// optionally with TLS.
+//
+// The lines are buffered while disconnected, up to a maximum size, and sent
+// once connected again. It connects when sending, waiting between attempts
+// for a backoff doubling after each failure, so sending can block up to the
+// connection timeout: use it with WithWorkers to keep measurements from
+// blocking.
+type TCPTransport struct {
+	addr string
+	cfg  tcpConfig
+
+	mu sync.Mutex
+	// conn is nil while disconnected.
+	conn      net.Conn
+	connected bool
+	closed    bool
+	// buf are the lines not sent yet.
+	buf      []byte
+	backoff  time.Duration
+	nextDial time.Time
+
+	sent       atomic.Int64
+	dropped    atomic.Int64
+	errors     atomic.Int64
+	reconnects atomic.Int64
+}
+
+var _ Transport = &TCPTransport{}
+
+// NewTCPTransport returns a TCPTransport sending to addr, of the format
+// "host:port". It connects on the first Send.
+func NewTCPTransport(addr string, opts ...TCPOption) *TCPTransport {
+	cfg := tcpConfig{
+		MinBackoff: defaultTCPMinBackoff,
+		MaxBackoff: defaultTCPMaxBackoff,
+		Timeout:    defaultTCPTimeout,
+		BufferSize: defaultTCPBufferSize,
+	}
+	for _, opt := range opts {
+		cfg = opt.apply(cfg)
+	}
+	return &TCPTransport{
+		addr:    addr,
+		cfg:     cfg,
+		buf:     make([]byte, 0, initialTCPBufferSize),
+		backoff: cfg.MinBackoff,
+	}
+}
+
+// Send buffers data as a line, and sends the buffered lines if connected or
+// if the backoff elapsed. It returns an error only if the line is dropped
+// because the buffer is full.
+func (t *TCPTransport) Send(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return 0, errTransportClosed
+	}
+	if len(t.buf) > 0 && len(t.buf)+len(data)+1 > t.cfg.BufferSize {
+		t.dropped.Add(1)
+		return 0, errTCPBufferFull
+	}
+	t.buf = append(t.buf, data...)
+	t.buf = append(t.buf, '\n')
+
+	if t.conn != nil || !time.Now().Before(t.nextDial) {
+		_ = t.flush(context.Background())
+	}
+	return len(data), nil
+}
+
+// Flush connects if disconnected, regardless of the backoff, and sends the
+// buffered lines.
+func (t *TCPTransport) Flush() error {
+	return t.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush, but gives up connecting and writing once ctx
+// is done, if that is before the timeout.
+func (t *TCPTransport) FlushContext(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed || len(t.buf) == 0 {
+		return nil
+	}
+	return t.flush(ctx)
+}
+
+// flush connects if needed and writes the buffered lines, until ctx is done
+// or the timeout elapses. t.mu must be held.
+func (t *TCPTransport) flush(ctx context.Context) error {
+	if t.conn == nil {
+		if err := t.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(t.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = t.conn.SetWriteDeadline(deadline)
+	n, err := t.conn.Write(t.buf)
+	if err != nil {
+		t.disconnect(err)
+		t.discard(n)
+		return err
+	}
+	t.sent.Add(1)
+	t.buf = t.buf[:0]
+	return nil
+}
+
+// discard removes the n bytes written of the buffered lines, and the rest of
+// the line cut by the failed write. t.mu must be held.
+func (t *TCPTransport) discard(n int) {
+	if n == 0 {
+		return
+	}
+	end := n
+	if t.buf[n-1] != '\n' {
+		t.dropped.Add(1)
+		end = len(t.buf)
+		if i := bytes.IndexByte(t.buf[n:], '\n'); i >= 0 {
+			end = n + i + 1
+		}
+	}
+	t.buf = t.buf[:copy(t.buf, t.buf[end:])]
+}
+
+// connect connects and resets the backoff, or schedules the next attempt.
+// t.mu must be held.
+func (t *TCPTransport) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: t.cfg.Timeout}
+
+	var conn net.Conn
+	var err error
+	if t.cfg.TLSConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: t.cfg.TLSConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", t.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", t.addr)
+	}
+	if err != nil {
+		t.errors.Add(1)
+		t.nextDial = time.Now().Add(t.backoff)
+		t.backoff = min(t.backoff*2, t.cfg.MaxBackoff)
+		t.notify(ConnectionStateDisconnected, err)
+		return err
+	}
+
+	if t.connected {
+		t.reconnects.Add(1)
+	}
+	t.conn = conn
+	t.connected = true
+	t.backoff = t.cfg.MinBackoff
+	t.notify(ConnectionStateConnected, nil)
+	return nil
+}
+
+// disconnect closes the connection after err. t.mu must be held.
+func (t *TCPTransport) disconnect(err error) {
+	t.errors.Add(1)
+	_ = t.conn.Close()
+	t.conn = nil
+	t.notify(ConnectionStateDisconnected, err)
+}
+
+func (t *TCPTransport) notify(state ConnectionState, err error) {
+	if t.cfg.StateCallback != nil {
+		t.cfg.StateCallback(state, err)
+	}
+}
+
+// Close sends the buffered lines if connected, and closes the connection.
+func (t *TCPTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if t.conn == nil {
+		return nil
+	}
+
+	var err error
+	if len(t.buf) > 0 {
+		err = t.flush(context.Background())
+	}
+	if t.conn != nil {
+		if cerr := t.conn.Close(); err == nil {
+			err = cerr
+		}
+		t.conn = nil
+	}
+	return err
+}
+
+// Stats returns the counters of the transport.
+func (t *TCPTransport) Stats() TCPStats {
+	return TCPStats{
+		Sent:       t.sent.Load(),
+		Dropped:    t.dropped.Load(),
+		Errors:     t.errors.Load(),
+		Reconnects: t.reconnects.Load(),
+	}
+}
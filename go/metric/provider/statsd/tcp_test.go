@@ -0,0 +1,227 @@
+package statsd
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpServer collects the lines received by a TCP listener.
+type tcpServer struct {
+	ln    net.Listener
+	lines chan string
+}
+
+func newTCPServer(t *testing.T, ln net.Listener) *tcpServer {
+	s := &tcpServer{ln: ln, lines: make(chan string, 100)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewScanner(conn)
+				for r.Scan() {
+					s.lines <- r.Text()
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *tcpServer) read(t *testing.T, n int) []string {
+	var got []string
+	for len(got) < n {
+		select {
+		case line := <-s.lines:
+			got = append(got, line)
+		case <-time.After(time.Second):
+			t.Fatalf("received %q, want %d lines", got, n)
+		}
+	}
+	return got
+}
+
+func TestTCPTransport(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	srv := newTCPServer(t, ln)
+
+	var mu sync.Mutex
+	var states []ConnectionState
+	transport := NewTCPTransport(addr,
+		WithTCPBackoff(time.Millisecond, time.Millisecond),
+		WithTCPStateCallback(func(state ConnectionState, _ error) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, state)
+		}),
+	)
+	defer transport.Close()
+
+	for _, line := range []string{"a:1|c", "b:2|c\nc:3|c"} {
+		n, err := transport.Send([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.Equal(t, []string{"a:1|c", "b:2|c", "c:3|c"}, srv.read(t, 3))
+
+	// The lines are buffered while the server is down. The first write to
+	// a connection closed by the server can succeed, so the failed write is
+	// simulated.
+	require.NoError(t, ln.Close())
+	transport.mu.Lock()
+	transport.disconnect(net.ErrClosed)
+	transport.mu.Unlock()
+	_, err = transport.Send([]byte("d:4|c"))
+	require.NoError(t, err)
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	srv = newTCPServer(t, ln)
+	require.NoError(t, transport.Flush())
+	assert.Equal(t, []string{"d:4|c"}, srv.read(t, 1))
+
+	stats := transport.Stats()
+	assert.Equal(t, int64(1), stats.Reconnects)
+	assert.Zero(t, stats.Dropped)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, ConnectionStateConnected, states[0])
+	assert.Equal(t, ConnectionStateConnected, states[len(states)-1])
+	assert.Contains(t, states, ConnectionStateDisconnected)
+}
+
+func TestTCPTransportBackoffAndBuffer(t *testing.T) {
+	// Reserve an address nobody listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	transport := NewTCPTransport(addr, WithTCPBackoff(time.Hour, time.Hour), WithTCPBufferSize(12))
+	defer transport.Close()
+
+	// The first send fails to connect, the next ones wait for the backoff.
+	_, err = transport.Send([]byte("a:1|c"))
+	require.NoError(t, err)
+	_, err = transport.Send([]byte("b:2|c"))
+	require.NoError(t, err)
+	_, err = transport.Send([]byte("c:3|c"))
+	require.ErrorIs(t, err, errTCPBufferFull)
+	assert.Equal(t, TCPStats{Dropped: 1, Errors: 1}, transport.Stats())
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	srv := newTCPServer(t, ln)
+	require.NoError(t, transport.Flush())
+	assert.Equal(t, []string{"a:1|c", "b:2|c"}, srv.read(t, 2))
+}
+
+func TestTCPTransportDiscard(t *testing.T) {
+	transport := NewTCPTransport("127.0.0.1:0")
+	transport.buf = []byte("a:1|c\nb:2|c\nc:3|c\n")
+	transport.discard(8)
+	assert.Equal(t, "c:3|c\n", string(transport.buf))
+	assert.Equal(t, int64(1), transport.Stats().Dropped)
+
+	transport.discard(6)
+	assert.Empty(t, transport.buf)
+	assert.Equal(t, int64(1), transport.Stats().Dropped)
+}
+
+func TestTCPTransportFlushContext(t *testing.T) {
+	// The server accepts the connections but never answers the TLS
+	// handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	transport := NewTCPTransport(ln.Addr().String(), WithTCPTLSConfig(&tls.Config{}), WithTCPTimeout(time.Hour))
+	defer transport.Close()
+	transport.buf = append(transport.buf, "a:1|c\n"...)
+	mp := NewMeterProvider(WithTransport(transport))
+
+	// The flush gives up at the deadline of ctx, not after the timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.ErrorIs(t, mp.ForceFlush(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, "a:1|c\n", string(transport.buf))
+	assert.Equal(t, int64(1), transport.Stats().Errors)
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestProviderTCPEndpointTLS(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	srv := newTCPServer(t, ln)
+
+	connected := make(chan struct{}, 1)
+	mp := NewMeterProvider(
+		WithEndpoint("tcp://"+ln.Addr().String()),
+		WithTLSConfig(&tls.Config{RootCAs: pool}),
+		WithConnectionStateCallback(func(state ConnectionState, _ error) {
+			if state == ConnectionStateConnected {
+				connected <- struct{}{}
+			}
+		}),
+		WithResourceTags(false),
+	)
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+	ctr.Add(context.Background(), 1)
+	require.NoError(t, mp.Shutdown(context.Background()))
+
+	<-connected
+	assert.Equal(t, []string{"hits:1|c"}, srv.read(t, 1))
+	assert.Equal(t, int64(1), mp.Stats().TCP.Sent)
+}
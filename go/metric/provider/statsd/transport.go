@@ -0,0 +1,76 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var errTransportClosed = errors.New("transport closed")
+
+// Transport sends encoded stats to a StatsD server. Each call to Send sends
+// a line, or several separated by newlines, and must not retain data.
+//
+// It has the method set of statsd.Sender, so the senders of
+// cactus/go-statsd-client can be used as transports.
+type Transport interface {
+	Send(data []byte) (int, error)
+	Close() error
+}
+
+// NewTransport returns the Transport of endpoint, which is either
+// "udp://host:port" or "host:port" for NewUDPTransport, "tcp://host:port"
+// for NewTCPTransport with opts, or "unix:///path/to/socket" or
+// "unixgram:///path/to/socket" for NewUDSTransport.
+func NewTransport(endpoint string, opts ...TCPOption) (Transport, error) {
+	scheme, addr, ok := strings.Cut(endpoint, "://")
+	if !ok {
+		return NewUDPTransport(endpoint)
+	}
+	switch scheme {
+	case "udp":
+		return NewUDPTransport(addr)
+	case "tcp":
+		return NewTCPTransport(addr, opts...), nil
+	case "unix", "unixgram":
+		return NewUDSTransport(scheme, addr)
+	default:
+		return nil, fmt.Errorf("unsupported endpoint scheme %q in %q", scheme, endpoint)
+	}
+}
+
+// udpTransport sends each line in a UDP datagram.
+type udpTransport struct {
+	conn net.Conn
+}
+
+// NewUDPTransport returns a Transport sending each line in a UDP datagram to
+// addr, of the format "host:port".
+func NewUDPTransport(addr string) (Transport, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &udpTransport{conn: conn}, nil
+}
+
+func (t *udpTransport) Send(data []byte) (int, error) {
+	return t.conn.Write(data)
+}
+
+func (t *udpTransport) Close() error {
+	return t.conn.Close()
+}
+
+// errTransport is the transport of an endpoint failing to be created. It
+// returns the error.
+type errTransport struct{ err error }
+
+func (t errTransport) Send([]byte) (int, error) {
+	return 0, t.err
+}
+
+func (t errTransport) Close() error {
+	return nil
+}
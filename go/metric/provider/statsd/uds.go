@@ -0,0 +1,190 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultUDSWriteTimeout = time.Millisecond * 100
+
+// UDSStats are the error counters of a UDSTransport.
+type UDSStats struct {
+	// Sent is the number of successful writes.
+	Sent int64
+	// BufferFull is the number of writes dropped because the socket buffer
+	// was full, which happens when the agent does not read fast enough.
+	BufferFull int64
+	// ConnectErrors is the number of failed connections, which happen when
+	// the socket does not exist or the agent does not listen on it.
+	ConnectErrors int64
+	// WriteErrors is the number of writes failing for other reasons.
+	WriteErrors int64
+	// Reconnects is the number of connections after the first one, which
+	// happen when the socket is recreated.
+	Reconnects int64
+}
+
+// UDSTransport sends stats to a unix domain socket. With the "unixgram"
+// network, each Send is a datagram, which can be as large as MTUUDS. With the
+// "unix" network, it is a stream of lines terminated by newlines.
+//
+// It connects again after the connection fails, so it keeps sending when
+// the agent recreates the socket. Writes time out after 100ms instead of
+// blocking when the agent does not read fast enough.
+type UDSTransport struct {
+	network string
+	path    string
+	timeout time.Duration
+
+	mu sync.Mutex
+	// conn is nil until connected, or after a failure.
+	conn      net.Conn
+	connected bool
+	closed    bool
+	// frame is the line written with the "unix" network.
+	frame []byte
+
+	sent          atomic.Int64
+	bufferFull    atomic.Int64
+	connectErrors atomic.Int64
+	writeErrors   atomic.Int64
+	reconnects    atomic.Int64
+}
+
+var _ Transport = &UDSTransport{}
+
+// NewUDSTransport returns a UDSTransport sending to the socket at path with
+// network, "unix" or "unixgram". It does not fail if the socket does not
+// exist yet, as the agent may start after the application.
+func NewUDSTransport(network, path string) (*UDSTransport, error) {
+	if network != "unix" && network != "unixgram" {
+		return nil, fmt.Errorf("unsupported unix domain socket network %q", network)
+	}
+	ret := &UDSTransport{
+		network: network,
+		path:    path,
+		timeout: defaultUDSWriteTimeout,
+	}
+
+	ret.mu.Lock()
+	defer ret.mu.Unlock()
+	_ = ret.connect()
+
+	return ret, nil
+}
+
+// Send writes data to the socket, connecting again and retrying once if the
+// connection failed.
+func (t *UDSTransport) Send(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return 0, errTransportClosed
+	}
+	if t.network == "unix" {
+		t.frame = append(append(t.frame[:0], data...), '\n')
+		if _, err := t.write(t.frame); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	return t.write(data)
+}
+
+// write writes data, connecting if needed. A failed connection is retried
+// once. t.mu must be held.
+func (t *UDSTransport) write(data []byte) (int, error) {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if t.conn == nil {
+			if err = t.connect(); err != nil {
+				return 0, err
+			}
+		}
+
+		var n int
+		_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+		n, err = t.conn.Write(data)
+		if err == nil {
+			t.sent.Add(1)
+			return n, nil
+		}
+
+		if isBufferFull(err) {
+			t.bufferFull.Add(1)
+			if t.network == "unix" && n > 0 {
+				// The line is cut, so the stream must start over.
+				t.close()
+			}
+			return 0, err
+		}
+
+		t.writeErrors.Add(1)
+		t.close()
+	}
+	return 0, err
+}
+
+// connect connects to the socket. t.mu must be held.
+func (t *UDSTransport) connect() error {
+	conn, err := net.DialTimeout(t.network, t.path, t.timeout)
+	if err != nil {
+		t.connectErrors.Add(1)
+		return err
+	}
+	if t.connected {
+		t.reconnects.Add(1)
+	}
+	t.conn = conn
+	t.connected = true
+	return nil
+}
+
+// close closes the connection. t.mu must be held.
+func (t *UDSTransport) close() {
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// Close closes the connection.
+func (t *UDSTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// Stats returns the error counters of the transport.
+func (t *UDSTransport) Stats() UDSStats {
+	return UDSStats{
+		Sent:          t.sent.Load(),
+		BufferFull:    t.bufferFull.Load(),
+		ConnectErrors: t.connectErrors.Load(),
+		WriteErrors:   t.writeErrors.Load(),
+		Reconnects:    t.reconnects.Load(),
+	}
+}
+
+// isBufferFull returns whether err is a write failing because the socket
+// buffer is full.
+func isBufferFull(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.ENOBUFS)
+}
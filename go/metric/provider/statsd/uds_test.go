@@ -0,0 +1,132 @@
+package statsd
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketPath returns the path of a socket in a temporary directory, short
+// enough for the limit of unix domain socket paths.
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "uds")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "statsd.sock")
+}
+
+func listenUnixgram(t *testing.T, path string) net.PacketConn {
+	conn, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	return conn
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, MTUUDS)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestUDSTransportUnixgram(t *testing.T) {
+	path := socketPath(t)
+	conn := listenUnixgram(t, path)
+
+	transport, err := NewTransport("unixgram://" + path)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	// Datagrams can be larger than over UDP.
+	bs := NewBatchSender(transport, MTUUDS, time.Hour)
+	line := make([]byte, 1000)
+	for i := range line {
+		line[i] = 'a'
+	}
+	for i := 0; i < 5; i++ {
+		_, err = bs.Send(line)
+		require.NoError(t, err)
+	}
+	require.NoError(t, bs.Flush())
+	assert.Len(t, readDatagram(t, conn), 5*1000+4)
+
+	// The socket is recreated by the agent.
+	require.NoError(t, conn.Close())
+	require.NoError(t, os.Remove(path))
+	conn = listenUnixgram(t, path)
+	defer conn.Close()
+
+	_, err = transport.Send([]byte("a:1|c"))
+	require.NoError(t, err)
+	assert.Equal(t, "a:1|c", readDatagram(t, conn))
+
+	stats := transport.(*UDSTransport).Stats()
+	assert.Equal(t, int64(2), stats.Sent)
+	assert.Equal(t, int64(1), stats.Reconnects)
+}
+
+func TestUDSTransportUnix(t *testing.T) {
+	path := socketPath(t)
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	transport, err := NewUDSTransport("unix", path)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, line := range []string{"a:1|c", "b:2|g"} {
+		n, err := transport.Send([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+
+	r := bufio.NewScanner(conn)
+	var got []string
+	for len(got) < 2 && r.Scan() {
+		got = append(got, r.Text())
+	}
+	assert.Equal(t, []string{"a:1|c", "b:2|g"}, got)
+}
+
+func TestUDSTransportConnectError(t *testing.T) {
+	transport, err := NewUDSTransport("unixgram", socketPath(t))
+	require.NoError(t, err)
+
+	_, err = transport.Send([]byte("a:1|c"))
+	require.Error(t, err)
+	assert.Equal(t, UDSStats{ConnectErrors: 2}, transport.Stats())
+
+	require.NoError(t, transport.Close())
+	_, err = transport.Send([]byte("a:1|c"))
+	require.ErrorIs(t, err, errTransportClosed)
+}
+
+func TestProviderEndpoint(t *testing.T) {
+	path := socketPath(t)
+	conn := listenUnixgram(t, path)
+	defer conn.Close()
+
+	mp := NewMeterProvider(WithEndpoint("unixgram://"+path), WithResourceTags(false))
+	ctr, err := mp.Meter("").Int64Counter("hits")
+	require.NoError(t, err)
+	ctr.Add(context.Background(), 1)
+
+	assert.Equal(t, "hits:1|c", readDatagram(t, conn))
+	assert.Equal(t, int64(1), mp.Stats().UDS.Sent)
+	require.NoError(t, mp.Shutdown(context.Background()))
+
+	_, err = NewTransport("http://localhost")
+	require.EqualError(t, err, `unsupported endpoint scheme "http" in "http://localhost"`)
+}
@@ -1,20 +1,79 @@
 package statsd
 
 import (
+	"sync"
+
 	"github.com/cactus/go-statsd-client/v5/statsd"
 	"go.opentelemetry.io/otel/attribute"
 )
 
-func collectTags(provider *MeterProvider, attrs attribute.Set) []statsd.Tag {
-	var ret []statsd.Tag
-
-	for _, attr := range provider.resource.Attributes() {
-		ret = append(ret, statsd.Tag{string(attr.Key), attr.Value.Emit()})
+// collectTags returns the tags of a measurement of s with attrs. The returned
+// slice may be shared and must not be modified.
+func collectTags(provider *MeterProvider, s *stream, attrs attribute.Set) []statsd.Tag {
+	key := attrs.Equivalent()
+	if tags, ok := s.tagCache.get(key); ok {
+		return tags
 	}
+
+	ret := make([]statsd.Tag, 0, len(provider.resourceTags)+len(s.tags)+attrs.Len())
+	ret = append(ret, provider.resourceTags...)
+	ret = append(ret, s.tags...)
 	aiter := attrs.Iter()
 	for aiter.Next() {
-		ret = append(ret, statsd.Tag{string(aiter.Attribute().Key), aiter.Attribute().Value.Emit()})
+		ret = append(ret, provider.sanitizeTag(string(aiter.Attribute().Key), aiter.Attribute().Value.Emit()))
 	}
 
+	s.tagCache.put(key, ret)
 	return ret
 }
+
+// maxCachedTagSets is the maximum number of attribute sets a tagCache holds.
+// Tags of other attribute sets are built on each measurement.
+const maxCachedTagSets = 1000
+
+// tagCache holds the tags of the attribute sets of a stream, so they are only
+// built once.
+//
+// It is safe to use concurrently.
+type tagCache struct {
+	mu   sync.RWMutex
+	tags map[attribute.Distinct][]statsd.Tag
+}
+
+func (c *tagCache) get(key attribute.Distinct) ([]statsd.Tag, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tags, ok := c.tags[key]
+	return tags, ok
+}
+
+func (c *tagCache) put(key attribute.Distinct, tags []statsd.Tag) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tags == nil {
+		c.tags = make(map[attribute.Distinct][]statsd.Tag)
+	}
+	if len(c.tags) < maxCachedTagSets {
+		c.tags[key] = tags
+	}
+}
+
+// durationUnitToMillis returns the factor converting values in unit to
+// milliseconds, if unit is a UCUM duration unit.
+func durationUnitToMillis(unit string) (float64, bool) {
+	switch unit {
+	case "ns":
+		return 1e-6, true
+	case "us":
+		return 1e-3, true
+	case "ms":
+		return 1, true
+	case "s":
+		return 1e3, true
+	case "min":
+		return 60e3, true
+	case "h":
+		return 3600e3, true
+	}
+	return 0, false
+}
@@ -0,0 +1,40 @@
+package statsd
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// valueMap holds a value for each distinct attribute set of an instrument.
+//
+// It is safe to use concurrently.
+type valueMap[N int64 | float64] struct {
+	sync.Mutex
+	values map[attribute.Distinct]N
+}
+
+func newValueMap[N int64 | float64]() *valueMap[N] {
+	return &valueMap[N]{values: make(map[attribute.Distinct]N)}
+}
+
+// add adds val to the value stored for attrs and returns the new total.
+func (m *valueMap[N]) add(attrs attribute.Set, val N) N {
+	m.Lock()
+	defer m.Unlock()
+	key := attrs.Equivalent()
+	total := m.values[key] + val
+	m.values[key] = total
+	return total
+}
+
+// swap stores val for attrs and returns the previously stored value. ok is
+// false if no value was stored for attrs.
+func (m *valueMap[N]) swap(attrs attribute.Set, val N) (prev N, ok bool) {
+	m.Lock()
+	defer m.Unlock()
+	key := attrs.Equivalent()
+	prev, ok = m.values[key]
+	m.values[key] = val
+	return prev, ok
+}
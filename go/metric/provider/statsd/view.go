@@ -0,0 +1,110 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+var (
+	errEmptyView = errors.New("no criteria provided for view")
+	errMultiInst = errors.New("name replacement for multiple instruments")
+)
+
+// View overrides how the instruments it matches are sent. It returns the
+// Stream to use and true if it matches the instrument.
+//
+// Unlike the views of the OpenTelemetry SDK, an instrument is only sent once:
+// the first view matching it is used.
+type View func(sdkmetric.Instrument) (Stream, bool)
+
+// Stream describes how the measurements of an instrument are sent.
+type Stream struct {
+	// Name is the name the instrument is sent as, before the prefix and
+	// scope namespacing are applied.
+	Name string
+	// Drop drops all the measurements of the instrument.
+	Drop bool
+	// AttributeFilter keeps the attributes it returns true for. All
+	// attributes are kept if it is nil.
+	AttributeFilter attribute.Filter
+	// HistogramType is the StatsD type of a histogram. The type configured
+	// for the MeterProvider is used if it is zero.
+	HistogramType HistogramType
+	// SampleRate is the sample rate of a synchronous instrument. The rate
+	// configured for the MeterProvider is used if it is zero.
+	SampleRate float32
+	// CardinalityLimit is the maximum number of attribute sets of a
+	// synchronous instrument per collection interval. The limit configured for the MeterProvider is
+	// used if it is zero, and there is no limit if it is negative.
+	CardinalityLimit int
+}
+
+// NewView returns a View that applies the Stream mask to the instruments
+// matching criteria.
+//
+// The Name of criteria can contain the * and ? wildcards, matching any
+// sequence of characters and any single character. The Kind, Unit, and Scope
+// fields of criteria are only matched if not zero. The Description is not
+// matched.
+//
+// The Name of mask replaces the name of the instrument if it is not empty.
+// As a name cannot replace the name of multiple instruments, a View that
+// never matches is returned if criteria contains a wildcard and mask a Name.
+// Such a View is also returned if criteria is empty.
+func NewView(criteria sdkmetric.Instrument, mask Stream) View {
+	if criteria.Name == "" && criteria.Kind == 0 && criteria.Unit == "" &&
+		criteria.Scope.Name == "" && criteria.Scope.Version == "" && criteria.Scope.SchemaURL == "" {
+		otel.Handle(errEmptyView)
+		return emptyView
+	}
+
+	matchName := func(name string) bool { return criteria.Name == "" || criteria.Name == name }
+	if strings.ContainsAny(criteria.Name, "*?") {
+		if mask.Name != "" {
+			otel.Handle(fmt.Errorf("%w: dropping view with criteria %q", errMultiInst, criteria.Name))
+			return emptyView
+		}
+
+		pattern := regexp.QuoteMeta(criteria.Name)
+		pattern = "^" + pattern + "$"
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		re := regexp.MustCompile(pattern)
+		matchName = re.MatchString
+	}
+
+	return func(i sdkmetric.Instrument) (Stream, bool) {
+		if !matchName(i.Name) ||
+			(criteria.Kind != 0 && criteria.Kind != i.Kind) ||
+			(criteria.Unit != "" && criteria.Unit != i.Unit) ||
+			(criteria.Scope.Name != "" && criteria.Scope.Name != i.Scope.Name) ||
+			(criteria.Scope.Version != "" && criteria.Scope.Version != i.Scope.Version) ||
+			(criteria.Scope.SchemaURL != "" && criteria.Scope.SchemaURL != i.Scope.SchemaURL) {
+			return Stream{}, false
+		}
+		ret := mask
+		if ret.Name == "" {
+			ret.Name = i.Name
+		}
+		return ret, true
+	}
+}
+
+func emptyView(sdkmetric.Instrument) (Stream, bool) {
+	return Stream{}, false
+}
+
+// filter returns the attributes of attrs kept by the AttributeFilter.
+func (s Stream) filter(attrs attribute.Set) attribute.Set {
+	if s.AttributeFilter == nil {
+		return attrs
+	}
+	ret, _ := attrs.Filter(s.AttributeFilter)
+	return ret
+}
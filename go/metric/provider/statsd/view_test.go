@@ -0,0 +1,204 @@
+package statsd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
+	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestNewView(t *testing.T) {
+	inst := sdkmetric.Instrument{
+		Name:  "http.server.duration",
+		Unit:  "s",
+		Kind:  sdkmetric.InstrumentKindHistogram,
+		Scope: instrumentation.Scope{Name: "otelhttp", Version: "v1"},
+	}
+
+	testCases := []struct {
+		name     string
+		criteria sdkmetric.Instrument
+		mask     Stream
+		match    bool
+		wantName string
+	}{
+		{
+			name:     "Name",
+			criteria: sdkmetric.Instrument{Name: "http.server.duration"},
+			match:    true,
+			wantName: "http.server.duration",
+		},
+		{
+			name:     "Rename",
+			criteria: sdkmetric.Instrument{Name: "http.server.duration"},
+			mask:     Stream{Name: "latency"},
+			match:    true,
+			wantName: "latency",
+		},
+		{
+			name:     "Wildcard",
+			criteria: sdkmetric.Instrument{Name: "http.*.dura?ion"},
+			match:    true,
+			wantName: "http.server.duration",
+		},
+		{
+			name:     "WildcardNoMatch",
+			criteria: sdkmetric.Instrument{Name: "rpc.*"},
+		},
+		{
+			name:     "WildcardRename",
+			criteria: sdkmetric.Instrument{Name: "http.*"},
+			mask:     Stream{Name: "latency"},
+		},
+		{
+			name:     "KindAndUnit",
+			criteria: sdkmetric.Instrument{Kind: sdkmetric.InstrumentKindHistogram, Unit: "s"},
+			match:    true,
+			wantName: "http.server.duration",
+		},
+		{
+			name:     "OtherKind",
+			criteria: sdkmetric.Instrument{Name: "http.server.duration", Kind: sdkmetric.InstrumentKindCounter},
+		},
+		{
+			name:     "Scope",
+			criteria: sdkmetric.Instrument{Scope: instrumentation.Scope{Name: "otelhttp"}},
+			match:    true,
+			wantName: "http.server.duration",
+		},
+		{
+			name:     "OtherScopeVersion",
+			criteria: sdkmetric.Instrument{Scope: instrumentation.Scope{Name: "otelhttp", Version: "v2"}},
+		},
+		{
+			name: "Empty",
+			mask: Stream{Drop: true},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := NewView(tt.criteria, tt.mask)(inst)
+			require.Equal(t, tt.match, ok)
+			assert.Equal(t, tt.wantName, s.Name)
+		})
+	}
+}
+
+func TestProviderViews(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 1, F: 1.0, Tags: []statsd.Tag{{"code", "200"}}},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 2, F: 1.0, Tags: []statsd.Tag{{"code", "200"}}},
+		mocks.MockStatSenderMethod{Method: "Raw", S: "size", S2: "512|h", F: 0.5},
+	)
+
+	mp := NewMeterProvider(
+		WithStatsdClient(rs),
+		WithResource(resource.Empty()),
+		WithView(
+			NewView(
+				sdkmetric.Instrument{Name: "http.server.request.count"},
+				Stream{Name: "requests", AttributeFilter: attribute.NewAllowKeysFilter("code")},
+			),
+			NewView(
+				sdkmetric.Instrument{Scope: instrumentation.Scope{Name: "noisy"}},
+				Stream{Drop: true},
+			),
+			NewView(
+				sdkmetric.Instrument{Kind: sdkmetric.InstrumentKindHistogram},
+				Stream{HistogramType: HistogramTypeHistogram, SampleRate: 0.5},
+			),
+		),
+	)
+	fixedRand(t, 0)
+
+	m := mp.Meter("app")
+	ctr, err := m.Int64Counter("http.server.request.count")
+	require.NoError(t, err)
+	ctr.Add(ctx, 1, metric.WithAttributes(attribute.String("code", "200"), attribute.String("user_id", "1")))
+	ctr.Add(ctx, 2, metric.WithAttributes(attribute.String("code", "200"), attribute.String("user_id", "2")))
+
+	size, err := m.Int64Histogram("size")
+	require.NoError(t, err)
+	size.Record(ctx, 512)
+
+	noisy := mp.Meter("noisy")
+	noisyCtr, err := noisy.Int64Counter("noise")
+	require.NoError(t, err)
+	noisyCtr.Add(ctx, 1)
+
+	var called bool
+	noisyObs, err := noisy.Int64ObservableGauge("noise.gauge", metric.WithInt64Callback(func(context.Context, metric.Int64Observer) error {
+		called = true
+		return nil
+	}))
+	require.NoError(t, err)
+	_, err = noisy.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		called = true
+		o.ObserveInt64(noisyObs, 1)
+		return nil
+	}, noisyObs)
+	require.NoError(t, err)
+
+	require.NoError(t, mp.produce(ctx))
+	assert.False(t, called, "callback of dropped instrument called")
+
+	rs.CHECK(t)
+	for _, out := range rs.Output {
+		for _, tag := range out.Tags {
+			assert.NotEqual(t, "user_id", tag[0])
+		}
+	}
+}
+
+func TestProviderViewsObservableCounter(t *testing.T) {
+	ctx := context.Background()
+
+	rs := mocks.NewMockStatSender()
+	rs.EXPECT(
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 12, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 6, F: 1.0},
+		mocks.MockStatSenderMethod{Method: "Inc", S: "requests", I: 6, F: 1.0},
+	)
+
+	mp := NewMeterProvider(
+		WithStatsdClient(rs),
+		WithResource(resource.Empty()),
+		WithView(NewView(
+			sdkmetric.Instrument{Name: "requests"},
+			Stream{AttributeFilter: attribute.NewAllowKeysFilter()},
+		)),
+	)
+
+	// The observations made equal by the filter are added up before the
+	// delta from the previous collection is computed.
+	values := [][2]int64{{10, 2}, {15, 3}, {20, 4}}
+	cycle := 0
+	cback := func(_ context.Context, o metric.Int64Observer) error {
+		o.Observe(values[cycle][0], metric.WithAttributes(attribute.String("code", "200")))
+		o.Observe(values[cycle][1], metric.WithAttributes(attribute.String("code", "500")))
+		return nil
+	}
+	_, err := mp.Meter("").Int64ObservableCounter("requests", metric.WithInt64Callback(cback))
+	require.NoError(t, err)
+
+	for cycle = range values {
+		require.NoError(t, mp.produce(ctx))
+	}
+
+	rs.CHECK(t)
+	for _, out := range rs.Output {
+		assert.Empty(t, out.Tags)
+	}
+}
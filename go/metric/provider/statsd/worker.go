@@ -1,165 +1,431 @@
 package statsd
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cactus/go-statsd-client/v5/statsd"
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	errDropped        = errors.New("worker chan buffer full: dropping stats, see Stats for the count")
+	errWorkersStarted = errors.New("workers already started")
+	errWorkersStopped = errors.New("workers stopped")
+)
+
+// workerState is the lifecycle state of a workerStatSender.
+type workerState int
+
+const (
+	// workerIdle queues the jobs until the workers are started.
+	workerIdle workerState = iota
+	// workerRunning sends the jobs with the workers.
+	workerRunning
+	// workerStopped rejects the jobs until the workers are started again.
+	workerStopped
 )
 
 // worker
 type worker struct {
-	statsdClient statsd.StatSender
+	statsdClient StatSender
 	input        chan workerJob
 	stop         chan struct{}
+	abort        chan struct{}
+	done         func()
 }
 
-func newWorker(input chan workerJob, statsdClient statsd.StatSender) *worker {
+func newWorker(input chan workerJob, statsdClient StatSender, stop, abort chan struct{}, done func()) *worker {
 	return &worker{
 		statsdClient: statsdClient,
 		input:        input,
-		stop:         make(chan struct{}),
+		stop:         stop,
+		abort:        abort,
+		done:         done,
 	}
 }
 
-func (w *worker) startReceivingMetric() {
-	go w.pullMetric()
-}
-
-func (w *worker) stopReceivingMetric() {
-	w.stop <- struct{}{}
-}
-
+// pullMetric sends the jobs until stop is closed, then sends the jobs left in
+// input and returns. It returns without sending the jobs left once abort is
+// closed.
 func (w *worker) pullMetric() {
 	for {
 		select {
 		case m := <-w.input:
-			_ = m(w.statsdClient)
-		case <-w.stop:
+			w.send(m)
+		case <-w.abort:
 			return
+		case <-w.stop:
+			for {
+				select {
+				case <-w.abort:
+					return
+				default:
+				}
+				select {
+				case m := <-w.input:
+					w.send(m)
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
+func (w *worker) send(m workerJob) {
+	_ = m(w.statsdClient)
+	w.done()
+}
+
 // workerStatSender
 type workerStatSender struct {
-	statsdClient statsd.StatSender
-	workers      []*worker
+	statsdClient StatSender
+	workers      int
 	input        chan workerJob
+
+	backpressure BackpressurePolicy
+	timeout      time.Duration
+	// dropped counts the jobs dropped because of the backpressure policy.
+	dropped atomic.Int64
+
+	// lifecycle serializes Start and Stop.
+	lifecycle sync.Mutex
+	// stop is closed to stop the running workers, abort to stop them
+	// without sending the queued jobs, and running is done once they
+	// returned.
+	stop    chan struct{}
+	abort   chan struct{}
+	running sync.WaitGroup
+
+	// mu guards state, enqueuing, pending, the number of jobs queued or
+	// being sent, and idle, the channels closed once there are no pending
+	// jobs. enqueuing is the number of calls queuing a job, which is only
+	// incremented while the state is not workerStopped.
+	mu        sync.Mutex
+	state     workerState
+	enqueuing sync.WaitGroup
+	pending   int
+	idle      []chan struct{}
 }
 
-func newWorkerStatSender(workers int, bufferSize int, statsdClient statsd.StatSender) *workerStatSender {
+func newWorkerStatSender(workers int, bufferSize int, backpressure BackpressurePolicy, timeout time.Duration, statsdClient StatSender) *workerStatSender {
 	if bufferSize <= 0 {
 		bufferSize = workers * 10
 	}
-	ret := &workerStatSender{
+	return &workerStatSender{
 		statsdClient: statsdClient,
+		workers:      workers,
 		input:        make(chan workerJob, bufferSize),
+		backpressure: backpressure,
+		timeout:      timeout,
 	}
-	for i := 0; i < workers; i++ {
-		w := newWorker(ret.input, statsdClient)
-		ret.workers = append(ret.workers, w)
-	}
-	return ret
 }
 
+// Start starts the workers. It can be called again after Stop.
 func (w *workerStatSender) Start() error {
-	if len(w.workers) == 0 {
+	if w.workers <= 0 {
 		return errors.New("no workers")
 	}
-	for _, w := range w.workers {
-		w.startReceivingMetric()
+
+	w.lifecycle.Lock()
+	defer w.lifecycle.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == workerRunning {
+		return errWorkersStarted
 	}
+	w.startWorkers()
+	w.state = workerRunning
 	return nil
 }
 
+// Stop stops the workers once they sent every queued job. The jobs queued
+// after Stop are rejected until Start is called again.
 func (w *workerStatSender) Stop() error {
-	for _, w := range w.workers {
-		w.stopReceivingMetric()
+	return w.stopContext(context.Background())
+}
+
+// stopContext stops the workers like Stop. If ctx is done before the queued
+// jobs are sent, the jobs left are dropped, the workers return once they sent
+// their current job, and ctx.Err() is returned.
+func (w *workerStatSender) stopContext(ctx context.Context) error {
+	w.lifecycle.Lock()
+	defer w.lifecycle.Unlock()
+
+	w.mu.Lock()
+	if w.state == workerStopped {
+		w.mu.Unlock()
+		return nil
 	}
-	w.workers = nil
-	w.flush()
-	return nil
+	if w.state == workerIdle {
+		// Start the workers to send the jobs queued before Start and
+		// unblock the calls waiting for the full chan buffer.
+		w.startWorkers()
+	}
+	w.state = workerStopped
+	stop, abort := w.stop, w.abort
+	w.mu.Unlock()
+
+	// No job is queued once the pending calls returned, so the workers
+	// send every job before returning.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		w.enqueuing.Wait()
+		close(stop)
+		w.running.Wait()
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+	close(abort)
+	w.discard()
+	return ctx.Err()
 }
 
+// startWorkers starts the worker goroutines. w.mu must be held.
+func (w *workerStatSender) startWorkers() {
+	w.stop = make(chan struct{})
+	w.abort = make(chan struct{})
+	for i := 0; i < w.workers; i++ {
+		wk := newWorker(w.input, w.statsdClient, w.stop, w.abort, w.done)
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			wk.pullMetric()
+		}()
+	}
+}
+
+// flush sends the queued jobs.
 func (w *workerStatSender) flush() {
 	for {
 		select {
-		case m, ok := <-w.input:
-			if ok {
-				_ = m(w.statsdClient)
-			} else {
-				// channel closed
-				break
-			}
+		case m := <-w.input:
+			_ = m(w.statsdClient)
+			w.done()
 		default:
 			return
 		}
 	}
 }
 
+// discard drops the queued jobs, counting them as dropped.
+func (w *workerStatSender) discard() {
+	for {
+		select {
+		case <-w.input:
+			w.done()
+			w.dropped.Add(1)
+		default:
+			return
+		}
+	}
+}
+
+// wait waits until all the queued jobs are sent, or ctx is done. It sends the
+// jobs itself if the workers are not running.
+func (w *workerStatSender) wait(ctx context.Context) error {
+	w.mu.Lock()
+	if w.state != workerRunning {
+		w.mu.Unlock()
+		w.flush()
+		w.mu.Lock()
+	}
+	if w.pending == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	w.idle = append(w.idle, idle)
+	w.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues job to be sent by the workers, applying the backpressure
+// policy if the chan buffer is full. It rejects job after Stop.
+func (w *workerStatSender) enqueue(job workerJob) error {
+	w.mu.Lock()
+	if w.state == workerStopped {
+		w.mu.Unlock()
+		return errWorkersStopped
+	}
+	w.pending++
+	w.enqueuing.Add(1)
+	abort := w.abort
+	w.mu.Unlock()
+	defer w.enqueuing.Done()
+
+	select {
+	case w.input <- job:
+		return nil
+	default:
+	}
+
+	switch w.backpressure {
+	case BackpressureDropNewest:
+		return w.drop()
+	case BackpressureDropOldest:
+		for {
+			select {
+			case <-w.input:
+				_ = w.drop()
+			default:
+			}
+			select {
+			case w.input <- job:
+				return nil
+			default:
+			}
+		}
+	case BackpressureBlockTimeout:
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+		select {
+		case w.input <- job:
+			return nil
+		case <-timer.C:
+			return w.drop()
+		case <-abort:
+			return w.drop()
+		}
+	default:
+		select {
+		case w.input <- job:
+			return nil
+		case <-abort:
+			return w.drop()
+		}
+	}
+}
+
+// drop counts a queued job as dropped. The first drop is reported with
+// otel.Handle.
+func (w *workerStatSender) drop() error {
+	w.done()
+	if w.dropped.Add(1) == 1 {
+		otel.Handle(errDropped)
+	}
+	return errDropped
+}
+
+// done marks a queued job as sent.
+func (w *workerStatSender) done() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending--
+	if w.pending == 0 {
+		for _, idle := range w.idle {
+			close(idle)
+		}
+		w.idle = nil
+	}
+}
+
 func (w *workerStatSender) Inc(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Inc(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) Dec(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Dec(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) Gauge(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Gauge(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) GaugeDelta(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.GaugeDelta(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) Timing(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Timing(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) TimingDuration(s string, duration time.Duration, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.TimingDuration(s, duration, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) Set(s string, s2 string, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Set(s, s2, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) SetInt(s string, i int64, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.SetInt(s, i, f, tag...)
-	}
-	return nil
+	})
 }
 
 func (w *workerStatSender) Raw(s string, s2 string, f float32, tag ...statsd.Tag) error {
-	w.input <- func(sender statsd.StatSender) error {
+	return w.enqueue(func(sender StatSender) error {
 		return sender.Raw(s, s2, f, tag...)
-	}
-	return nil
+	})
+}
+
+func (w *workerStatSender) IncFloat(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.IncFloat(s, v, f, tag...)
+	})
+}
+
+func (w *workerStatSender) GaugeFloat(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.GaugeFloat(s, v, f, tag...)
+	})
+}
+
+func (w *workerStatSender) GaugeFloatDelta(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.GaugeFloatDelta(s, v, f, tag...)
+	})
+}
+
+func (w *workerStatSender) TimingFloat(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.TimingFloat(s, v, f, tag...)
+	})
+}
+
+func (w *workerStatSender) HistogramFloat(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.HistogramFloat(s, v, f, tag...)
+	})
+}
+
+func (w *workerStatSender) DistributionFloat(s string, v float64, f float32, tag ...statsd.Tag) error {
+	return w.enqueue(func(sender StatSender) error {
+		return sender.DistributionFloat(s, v, f, tag...)
+	})
 }
 
 // workerJob
-type workerJob func(statsd.StatSender) error
+type workerJob func(StatSender) error
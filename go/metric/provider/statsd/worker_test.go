@@ -1,10 +1,15 @@
 package statsd
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/SibrosTech/otel-statsd/go/metric/provider/statsd/mocks"
 	"github.com/cactus/go-statsd-client/v5/statsd"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,7 +34,7 @@ func TestWorkerStatSender(t *testing.T) {
 	rs := mocks.NewMockStatSender()
 	rs.EXPECT(tests...)
 
-	sender := newWorkerStatSender(2, 10, rs)
+	sender := newWorkerStatSender(2, 10, BackpressureBlock, 0, NewStatSender(rs))
 	err := sender.Start()
 	require.NoError(t, err)
 
@@ -43,3 +48,144 @@ func TestWorkerStatSender(t *testing.T) {
 
 	rs.CHECK(t)
 }
+
+func TestWorkerStatSenderBackpressure(t *testing.T) {
+	testCases := []struct {
+		name    string
+		policy  BackpressurePolicy
+		want    []int64
+		dropped int64
+	}{
+		{
+			name:    "DropNewest",
+			policy:  BackpressureDropNewest,
+			want:    []int64{1, 2},
+			dropped: 2,
+		},
+		{
+			name:    "DropOldest",
+			policy:  BackpressureDropOldest,
+			want:    []int64{3, 4},
+			dropped: 2,
+		},
+		{
+			name:    "BlockTimeout",
+			policy:  BackpressureBlockTimeout,
+			want:    []int64{1, 2},
+			dropped: 2,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mocks.NewMockStatSender()
+
+			// The workers are not started, so the chan buffer fills up.
+			sender := newWorkerStatSender(1, 2, tt.policy, time.Millisecond, NewStatSender(rs))
+			for i := int64(1); i <= 4; i++ {
+				err := sender.Inc("a", i, 1.0)
+				if i <= 2 || tt.policy == BackpressureDropOldest {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, errDropped)
+				}
+			}
+			require.NoError(t, sender.wait(context.Background()))
+
+			var got []int64
+			for _, out := range rs.Output {
+				got = append(got, out.I)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.dropped, sender.dropped.Load())
+		})
+	}
+}
+
+func TestWorkerStatSenderBlock(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	sender := newWorkerStatSender(1, 1, BackpressureBlock, 0, NewStatSender(rs))
+	require.NoError(t, sender.Inc("a", 1, 1.0))
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		_ = sender.Inc("a", 2, 1.0)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("Inc did not block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	require.NoError(t, sender.Start())
+	<-sent
+	require.NoError(t, sender.Stop())
+	assert.Len(t, rs.Output, 2)
+	assert.Zero(t, sender.dropped.Load())
+}
+
+func TestWorkerStatSenderLifecycle(t *testing.T) {
+	rs := mocks.NewMockStatSender()
+	sender := newWorkerStatSender(2, 10, BackpressureBlock, 0, NewStatSender(rs))
+
+	// Jobs queued before Start are sent by Stop.
+	require.NoError(t, sender.Inc("a", 1, 1.0))
+	require.NoError(t, sender.Stop())
+	require.Len(t, rs.Output, 1)
+
+	// Jobs are rejected after Stop.
+	require.ErrorIs(t, sender.Inc("a", 2, 1.0), errWorkersStopped)
+
+	// The workers can be started again.
+	require.NoError(t, sender.Start())
+	require.ErrorIs(t, sender.Start(), errWorkersStarted)
+	require.NoError(t, sender.Inc("a", 3, 1.0))
+	require.NoError(t, sender.Stop())
+	require.NoError(t, sender.Stop())
+
+	require.NoError(t, sender.Start())
+	require.NoError(t, sender.Inc("a", 4, 1.0))
+	require.NoError(t, sender.Stop())
+
+	var got []int64
+	for _, out := range rs.Output {
+		got = append(got, out.I)
+	}
+	assert.Equal(t, []int64{1, 3, 4}, got)
+}
+
+func TestWorkerStatSenderConcurrentStop(t *testing.T) {
+	for _, policy := range []BackpressurePolicy{BackpressureBlock, BackpressureDropNewest, BackpressureDropOldest} {
+		rs := mocks.NewMockStatSender()
+		sender := newWorkerStatSender(4, 2, policy, 0, NewStatSender(rs))
+		require.NoError(t, sender.Start())
+
+		var sent atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					if sender.Inc("a", 1, 1.0) == nil {
+						sent.Add(1)
+					}
+				}
+			}()
+		}
+
+		require.NoError(t, sender.Stop())
+		wg.Wait()
+
+		// Every accepted job is sent exactly once, or dropped later by
+		// BackpressureDropOldest.
+		want := sent.Load()
+		if policy == BackpressureDropOldest {
+			want -= sender.dropped.Load()
+		}
+		assert.Equal(t, want, int64(len(rs.Output)))
+		assert.Zero(t, sender.pending)
+	}
+}